@@ -0,0 +1,65 @@
+package querier
+
+// Builder builds a select query by chaining methods, as an alternative to passing options to
+// NewSelect. Create it with SelectFrom.
+type Builder struct {
+	query *Query
+}
+
+// SelectFrom starts building a select query on table.
+func SelectFrom(table DBTable) *Builder {
+	return &Builder{query: NewSelect(table, nil)}
+}
+
+// Fields adds fields to the select list.
+func (b *Builder) Fields(fields ...DBField) *Builder {
+	for _, field := range fields {
+		b.query.appendField(field, nil)
+	}
+
+	return b
+}
+
+func (b *Builder) Where(field DBField, operation DBOperation, params ...any) *Builder {
+	return b.Apply(Where(field, operation, params...))
+}
+
+func (b *Builder) Join(table DBTable, joinType JoinType, on, equal DBField) *Builder {
+	return b.Apply(Join(table, joinType, on, equal))
+}
+
+func (b *Builder) GroupBy(fields ...DBField) *Builder {
+	return b.Apply(GroupBy(fields...))
+}
+
+func (b *Builder) OrderBy(field DBField, order OrderByType) *Builder {
+	return b.Apply(OrderBy(field, order))
+}
+
+func (b *Builder) Limit(limit int) *Builder {
+	return b.Apply(Limit(limit))
+}
+
+func (b *Builder) Offset(offset int) *Builder {
+	return b.Apply(Offset(offset))
+}
+
+// Apply applies any other option, such as WithDialect or Or, to the query.
+func (b *Builder) Apply(opts ...QueryBuilderOption) *Builder {
+	b.query.Apply(opts...)
+	return b
+}
+
+// Query returns the query being built.
+func (b *Builder) Query() *Query {
+	return b.query
+}
+
+func (b *Builder) Build() (string, []any) {
+	return b.query.Build()
+}
+
+// BuildE works like Build but reports the problems found while building the query.
+func (b *Builder) BuildE() (string, []any, error) {
+	return b.query.BuildE()
+}
@@ -0,0 +1,51 @@
+package querier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	var (
+		users  DBTable = "users"
+		orders DBTable = "orders"
+
+		userID   DBField = "users.id"
+		userName DBField = "users.name"
+	)
+
+	t.Run("matches options", func(t *testing.T) {
+		query, params := SelectFrom(users).
+			Fields(userID, userName).
+			Join(orders, InnerJoin, userID, "orders.user_id").
+			Where(userName, Like, "a%").
+			Where("orders.total", GreaterThan, 10).
+			GroupBy(userID, userName).
+			OrderBy(userName, ASC).
+			Limit(10).
+			Offset(20).
+			Build()
+
+		expectedQuery, expectedParams := NewQuery(users, []DBField{userID, userName},
+			Join(orders, InnerJoin, userID, "orders.user_id"),
+			Where(userName, Like, "a%"),
+			Where("orders.total", GreaterThan, 10),
+			GroupBy(userID, userName),
+			OrderBy(userName, ASC),
+			Limit(10),
+			Offset(20),
+		)
+
+		require.Equal(t, expectedQuery, query)
+		require.Equal(t, expectedParams, params)
+		require.Equal(t, "SELECT users.id, users.name FROM users INNER JOIN orders ON users.id = orders.user_id WHERE users.name LIKE ? AND orders.total > ? GROUP BY users.id, users.name ORDER BY users.name ASC LIMIT ? OFFSET ?", query)
+	})
+
+	t.Run("apply options", func(t *testing.T) {
+		query, params, err := SelectFrom(users).Apply(WithDialect(Postgres)).Where(userID, In, 1, 2).Apply(Or(Where(userName, Equal, "a"), Where(userName, Equal, "b"))).BuildE()
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM users WHERE users.id IN ($1,$2) AND (users.name = $3 OR users.name = $4)", query)
+		require.Equal(t, []any{1, 2, "a", "b"}, params)
+	})
+}
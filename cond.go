@@ -0,0 +1,71 @@
+package querier
+
+import "strings"
+
+// Cond is a boolean expression of conditions that nest arbitrarily, built with Expr, AndCond and
+// OrCond and added to a query with WhereCond.
+type Cond interface {
+	render(q *Query) (string, []any)
+}
+
+type exprCond struct {
+	field     DBField
+	operation DBOperation
+	params    []any
+}
+
+// Expr is a condition comparing field to params, like Where does.
+func Expr(field DBField, operation DBOperation, params ...any) Cond {
+	return exprCond{field: field, operation: operation, params: params}
+}
+
+func (c exprCond) render(q *Query) (string, []any) {
+	return q.buildCondition(c.field, c.operation, c.params)
+}
+
+type groupCond struct {
+	separator string
+	conds     []Cond
+}
+
+// AndCond is true when all of conds are.
+func AndCond(conds ...Cond) Cond {
+	return groupCond{separator: " AND ", conds: conds}
+}
+
+// OrCond is true when any of conds is.
+func OrCond(conds ...Cond) Cond {
+	return groupCond{separator: " OR ", conds: conds}
+}
+
+func (c groupCond) render(q *Query) (string, []any) {
+	var (
+		conditions []string
+		params     []any
+	)
+
+	for _, cond := range c.conds {
+		condition, condParams := cond.render(q)
+		if condition == "" {
+			continue
+		}
+
+		conditions = append(conditions, condition)
+		params = append(params, condParams...)
+	}
+
+	res := strings.Join(conditions, c.separator)
+	if len(conditions) > 1 {
+		res = "(" + res + ")"
+	}
+
+	return res, params
+}
+
+// WhereCond adds cond to the conditions of the query. Its params are bound in the order the
+// conditions appear.
+func WhereCond(cond Cond) QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere(cond.render(q))
+	}
+}
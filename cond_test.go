@@ -0,0 +1,45 @@
+package querier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCond(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		a DBField = "users.a"
+		b DBField = "users.b"
+		c DBField = "users.c"
+		d DBField = "users.d"
+		e DBField = "users.e"
+	)
+
+	t.Run("nested tree", func(t *testing.T) {
+		cond := OrCond(
+			Expr(a, Equal, 1),
+			AndCond(
+				Expr(b, In, 2, 3),
+				OrCond(Expr(c, Equal, 4), Expr(d, IsNull)),
+			),
+			Expr(e, GreaterThan, 5),
+		)
+
+		query, params := NewQuery(users, nil, Where(a, NotEqual, 0), WhereCond(cond))
+		require.Equal(t, "SELECT * FROM users WHERE users.a <> ? AND (users.a = ? OR (users.b IN (?,?) AND (users.c = ? OR users.d IS NULL)) OR users.e > ?)", query)
+		require.Equal(t, []any{0, 1, 2, 3, 4, 5}, params)
+	})
+
+	t.Run("single conditions are not wrapped", func(t *testing.T) {
+		query, params := NewQuery(users, nil, WhereCond(AndCond(OrCond(Expr(a, Equal, 1)), AndCond())))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		_, _, err := NewQueryE(users, nil, WhereCond(OrCond(Expr(a, Equal, 1), Expr(b, In))))
+		require.ErrorIs(t, err, ErrEmptyIn)
+	})
+}
@@ -0,0 +1,101 @@
+package querier
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// DB is the subset of *sql.DB used to run queries. *sql.Tx, *sql.Conn and sqlx types implement it too.
+type DB interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// QueryContext builds q and runs it on db. args are the values of the inserted fields bound by the
+// caller, that is the ones not added with Value, and go before the params of the query. Nothing is
+// run if the query could not be built.
+func (q *Query) QueryContext(ctx context.Context, db DB, args ...any) (*sql.Rows, error) {
+	query, params, err := q.buildWithArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.QueryContext(ctx, query, params...)
+}
+
+// ExecContext builds q and executes it on db, binding args like QueryContext. Nothing is executed
+// if the query could not be built.
+func (q *Query) ExecContext(ctx context.Context, db DB, args ...any) (sql.Result, error) {
+	query, params, err := q.buildWithArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ExecContext(ctx, query, params...)
+}
+
+// buildWithArgs builds q and puts args, which must bind every placeholder left to the caller,
+// before its params.
+func (q *Query) buildWithArgs(args []any) (string, []any, error) {
+	query, params, err := q.BuildE()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if expected := q.callerBound(); len(args) != expected {
+		return "", nil, fmt.Errorf("%w: %d args for %d caller bound placeholders", ErrParamCount, len(args), expected)
+	}
+
+	return query, append(args[:len(args):len(args)], params...), nil
+}
+
+// BuildNamed works like BuildE but renders named placeholders, such as :uid, for the drivers
+// supporting them. Params given as sql.Named keep their name, the others are named after their
+// position, as in :p3. A name bound more than once is returned once, and must always be bound to
+// the same value. The values of inserted fields must be added with Value, as the ones bound by the
+// caller can not be named.
+func (q *Query) BuildNamed() (string, []sql.NamedArg, error) {
+	query, params, err := q.build()
+	if err == nil {
+		err = q.validatePlaceholders(query, params)
+	}
+
+	query, params = q.resolveOperators(query, params)
+	if unbound := q.callerBound(); unbound > 0 && err == nil {
+		err = fmt.Errorf("%w: %d inserted fields without a Value", ErrParamCount, unbound)
+	}
+
+	var (
+		args   []sql.NamedArg
+		values = make(map[string]any)
+		skip   = q.callerBound()
+	)
+
+	res, _ := replacePlaceholders(query, func(n int) string {
+		if n <= skip || n-skip > len(params) {
+			return "?"
+		}
+
+		param := params[n-skip-1]
+		arg, ok := param.(sql.NamedArg)
+		if !ok {
+			arg = sql.Named("p"+strconv.Itoa(n), param)
+		}
+
+		value, seen := values[arg.Name]
+		switch {
+		case !seen:
+			values[arg.Name] = arg.Value
+			args = append(args, arg)
+		case !reflect.DeepEqual(value, arg.Value) && err == nil:
+			err = fmt.Errorf("%w: :%s bound to %v and %v", ErrNamedParam, arg.Name, value, arg.Value)
+		}
+
+		return ":" + arg.Name
+	})
+
+	return res, args, err
+}
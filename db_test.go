@@ -0,0 +1,134 @@
+package querier
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockDB struct {
+	query string
+	args  []any
+	calls int
+}
+
+func (m *mockDB) QueryContext(_ context.Context, query string, args ...any) (*sql.Rows, error) {
+	m.query, m.args = query, args
+	m.calls++
+	return nil, nil
+}
+
+func (m *mockDB) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	m.query, m.args = query, args
+	m.calls++
+	return nil, nil
+}
+
+func TestDB(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		userID   DBField = "users.id"
+		userName DBField = "users.name"
+	)
+
+	t.Run("query", func(t *testing.T) {
+		db := &mockDB{}
+		_, err := NewSelect(users, []DBField{userName}, Where(userID, Equal, 1), Limit(10)).QueryContext(context.Background(), db)
+		require.NoError(t, err)
+		require.Equal(t, "SELECT users.name FROM users WHERE users.id = ? LIMIT ?", db.query)
+		require.Equal(t, []any{1, 10}, db.args)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		db := &mockDB{}
+		_, err := NewUpdateQuery(users, WithDialect(Postgres), Set(userName, "bla"), Where(userID, Equal, 1)).ExecContext(context.Background(), db)
+		require.NoError(t, err)
+		require.Equal(t, "UPDATE users SET name = $1 WHERE users.id = $2", db.query)
+		require.Equal(t, []any{"bla", 1}, db.args)
+	})
+
+	t.Run("exec insert and delete", func(t *testing.T) {
+		db := &mockDB{}
+		insert := NewInsertQuery(users, []DBField{userName}, WithDialect(Postgres), OnConflict([]DBField{"name"}, Set("status", "x")), Returning("id"))
+		_, err := insert.ExecContext(context.Background(), db, "bla")
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET status = $2 RETURNING id", db.query)
+		require.Equal(t, []any{"bla", "x"}, db.args)
+
+		_, err = insert.ExecContext(context.Background(), db)
+		require.ErrorIs(t, err, ErrParamCount)
+		require.Equal(t, 1, db.calls)
+
+		_, err = NewInsertQuery(users, nil, Value(userName, "bla")).ExecContext(context.Background(), db)
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name) VALUES (?)", db.query)
+		require.Equal(t, []any{"bla"}, db.args)
+
+		_, err = NewDeleteQuery(users, Where(userID, Equal, 1)).ExecContext(context.Background(), db)
+		require.NoError(t, err)
+		require.Equal(t, "DELETE FROM users WHERE users.id = ?", db.query)
+		require.Equal(t, []any{1}, db.args)
+	})
+
+	t.Run("build errors are not run", func(t *testing.T) {
+		db := &mockDB{}
+		_, err := NewSelect(users, nil, Where(userID, In)).QueryContext(context.Background(), db)
+		require.ErrorIs(t, err, ErrEmptyIn)
+
+		_, err = NewUpdateQuery(users).ExecContext(context.Background(), db)
+		require.ErrorIs(t, err, ErrEmptySet)
+
+		_, err = NewDeleteQuery(users, Where(userID, Equal, 1)).ExecContext(context.Background(), db, 2)
+		require.ErrorIs(t, err, ErrParamCount)
+		require.Zero(t, db.calls)
+	})
+}
+
+func TestBuildNamed(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		userID     DBField = "users.id"
+		userStatus DBField = "users.status"
+		userBoss   DBField = "users.boss_id"
+	)
+
+	t.Run("named params", func(t *testing.T) {
+		query, args, err := NewSelect(users, nil, Where(userID, Equal, sql.Named("uid", 5)), Where(userStatus, In, sql.Named("a", "active"), sql.Named("b", "banned")), Where(userBoss, NotEqual, sql.Named("uid", 5))).BuildNamed()
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM users WHERE users.id = :uid AND users.status IN (:a,:b) AND users.boss_id <> :uid", query)
+		require.Equal(t, []sql.NamedArg{sql.Named("uid", 5), sql.Named("a", "active"), sql.Named("b", "banned")}, args)
+	})
+
+	t.Run("positional params are named", func(t *testing.T) {
+		query, args, err := NewSelect(users, nil, Where(userID, Equal, sql.Named("uid", 5)), Limit(10)).BuildNamed()
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM users WHERE users.id = :uid LIMIT :p2", query)
+		require.Equal(t, []sql.NamedArg{sql.Named("uid", 5), sql.Named("p2", 10)}, args)
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		_, _, err := NewSelect(users, nil, Where(userID, In)).BuildNamed()
+		require.ErrorIs(t, err, ErrEmptyIn)
+	})
+
+	t.Run("name reused with another value", func(t *testing.T) {
+		_, _, err := NewSelect(users, nil, Where(userID, Equal, sql.Named("x", 1)), Where(userBoss, Equal, sql.Named("x", 2))).BuildNamed()
+		require.ErrorIs(t, err, ErrNamedParam)
+	})
+
+	t.Run("inserts", func(t *testing.T) {
+		query, args, err := NewInsertQuery(users, nil, Value("name", sql.Named("name", "bla")), OnConflict([]DBField{"name"}, Set("status", "v"))).BuildNamed()
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name) VALUES (:name) ON CONFLICT (name) DO UPDATE SET status = :p2", query)
+		require.Equal(t, []sql.NamedArg{sql.Named("name", "bla"), sql.Named("p2", "v")}, args)
+
+		query, args, err = NewInsertQuery(users, []DBField{"name"}, OnConflict([]DBField{"name"}, Set("status", "v"))).BuildNamed()
+		require.ErrorIs(t, err, ErrParamCount)
+		require.Equal(t, "INSERT INTO users (name) VALUES (?) ON CONFLICT (name) DO UPDATE SET status = :p2", query)
+		require.Equal(t, []sql.NamedArg{sql.Named("p2", "v")}, args)
+	})
+}
@@ -1,8 +1,15 @@
 package querier
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type DBTable string
@@ -13,13 +20,180 @@ const (
 	Count DBField = "COUNT(*)"
 )
 
+func CountOf(field DBField, distinct bool) DBField {
+	if distinct {
+		return DBField(fmt.Sprintf("COUNT(DISTINCT %s)", field))
+	}
+
+	return DBField(fmt.Sprintf("COUNT(%s)", field))
+}
+
+func Sum(field DBField) DBField {
+	return DBField(fmt.Sprintf("SUM(%s)", field))
+}
+
+func Avg(field DBField) DBField {
+	return DBField(fmt.Sprintf("AVG(%s)", field))
+}
+
+func Min(field DBField) DBField {
+	return DBField(fmt.Sprintf("MIN(%s)", field))
+}
+
+func Max(field DBField) DBField {
+	return DBField(fmt.Sprintf("MAX(%s)", field))
+}
+
+// GroupConcat concatenates the values of field in each group, separated by separator (MySQL).
+func GroupConcat(field DBField, separator string) DBField {
+	return DBField(fmt.Sprintf("GROUP_CONCAT(%s SEPARATOR %s)", field, quoteString(separator)))
+}
+
+// StringAgg concatenates the values of field in each group, separated by separator (Postgres).
+func StringAgg(field DBField, separator string) DBField {
+	return DBField(fmt.Sprintf("STRING_AGG(%s, %s)", field, quoteString(separator)))
+}
+
+// Coalesce returns the first of fields that is not NULL.
+func Coalesce(fields ...DBField) DBField {
+	return DBField("COALESCE(" + joinFields(fields, ", ") + ")")
+}
+
+// NullIf returns NULL when a equals b, and a otherwise.
+func NullIf(a, b DBField) DBField {
+	return DBField(fmt.Sprintf("NULLIF(%s, %s)", a, b))
+}
+
+// JSONGet returns the Postgres json value stored under key of field, as in data->'key'.
+func JSONGet(field DBField, key string) DBField {
+	return DBField(fmt.Sprintf("%s->%s", field, quoteString(key)))
+}
+
+// JSONText returns the Postgres json value stored under key of field as text, as in data->>'key'.
+func JSONText(field DBField, key string) DBField {
+	return DBField(fmt.Sprintf("%s->>%s", field, quoteString(key)))
+}
+
+// quoteString renders value as a SQL string literal.
+func quoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Alias returns table under a short alias, as in users u, so the fields of the query can be
+// written as u.id. Using the alias in the fields is up to the caller.
+func Alias(table DBTable, alias string) DBTable {
+	return DBTable(fmt.Sprintf("%s %s", table, alias))
+}
+
+// As names field alias in the results, as in COUNT(*) AS total. It works with any field or
+// expression, including the aggregates such as Count and Sum.
+func As(field DBField, alias string) DBField {
+	return DBField(fmt.Sprintf("%s AS %s", field, alias))
+}
+
+// CaseExpr builds a CASE expression for the select list. Create it with Case.
+type CaseExpr struct {
+	whens  []string
+	params []any
+	other  string
+	err    error
+}
+
+func Case() *CaseExpr {
+	return &CaseExpr{}
+}
+
+// When adds a branch returning result, a raw SQL expression, for the rows matching cond.
+func (c *CaseExpr) When(cond QueryBuilderOption, result string) *CaseExpr {
+	temp := &Query{}
+	temp.Apply(cond)
+	if c.err == nil {
+		c.err = temp.err
+	}
+
+	c.whens = append(c.whens, fmt.Sprintf("WHEN %s THEN %s", strings.Join(temp.where, " AND "), result))
+	c.params = append(c.params, temp.params...)
+
+	return c
+}
+
+// Else sets the result, a raw SQL expression, for the rows matching no branch.
+func (c *CaseExpr) Else(result string) *CaseExpr {
+	c.other = result
+	return c
+}
+
+// As adds the expression to the select list under alias, binding the params of its branches.
+func (c *CaseExpr) As(alias string) QueryBuilderOption {
+	return func(q *Query) {
+		if len(c.whens) == 0 {
+			q.addError(fmt.Errorf("%w: %s", ErrEmptyCase, alias))
+			return
+		}
+
+		expr := "CASE " + strings.Join(c.whens, " ")
+		if c.other != "" {
+			expr += " ELSE " + c.other
+		}
+
+		expr += " END"
+
+		q.addError(c.err)
+		q.appendField(As(DBField(expr), alias), c.params)
+	}
+}
+
+// RowNumber numbers the rows of each window, see Over.
+func RowNumber() DBField {
+	return "ROW_NUMBER()"
+}
+
+// WindowExpr builds a window function call for the select list. Create it with Over.
+type WindowExpr struct {
+	function    DBField
+	partitionBy []DBField
+	orderBy     []string
+}
+
+// Over computes function, such as RowNumber or Sum, over a window of rows.
+func Over(function DBField) *WindowExpr {
+	return &WindowExpr{function: function}
+}
+
+// PartitionBy splits the rows in windows by fields.
+func (w *WindowExpr) PartitionBy(fields ...DBField) *WindowExpr {
+	w.partitionBy = append(w.partitionBy, fields...)
+	return w
+}
+
+// OrderBy sorts the rows of each window by field.
+func (w *WindowExpr) OrderBy(field DBField, order OrderByType) *WindowExpr {
+	w.orderBy = append(w.orderBy, fmt.Sprintf("%s %s", field, order))
+	return w
+}
+
+// As returns the window expression as a select field named alias.
+func (w *WindowExpr) As(alias string) DBField {
+	var clauses []string
+	if len(w.partitionBy) > 0 {
+		clauses = append(clauses, "PARTITION BY "+joinFields(w.partitionBy, ", "))
+	}
+
+	if len(w.orderBy) > 0 {
+		clauses = append(clauses, "ORDER BY "+strings.Join(w.orderBy, ", "))
+	}
+
+	return As(DBField(fmt.Sprintf("%s OVER (%s)", w.function, strings.Join(clauses, " "))), alias)
+}
+
 type QueryOperation string
 
 const (
-	Insert QueryOperation = "INSERT INTO"
-	Select QueryOperation = "SELECT"
-	Update QueryOperation = "UPDATE"
-	Delete QueryOperation = "DELETE"
+	Insert   QueryOperation = "INSERT INTO"
+	Select   QueryOperation = "SELECT"
+	Update   QueryOperation = "UPDATE"
+	Delete   QueryOperation = "DELETE"
+	Truncate QueryOperation = "TRUNCATE TABLE"
 )
 
 type JoinType string
@@ -39,303 +213,2031 @@ const (
 	ASC  OrderByType = "ASC"
 )
 
+type NullsOrder string
+
+const (
+	NullsFirst NullsOrder = "NULLS FIRST"
+	NullsLast  NullsOrder = "NULLS LAST"
+)
+
 type DBOperation string
 
 const (
-	NotEqual       DBOperation = "NOT EQUAL"
+	NotEqual       DBOperation = "<>"
 	Equal          DBOperation = "="
 	LessOrEqual    DBOperation = "<="
 	LessThan       DBOperation = "<"
 	GreaterOrEqual DBOperation = ">="
 	GreaterThan    DBOperation = ">"
-	NotInt         DBOperation = "NOT IN"
+	NotIn          DBOperation = "NOT IN"
 	In             DBOperation = "IN"
 	Like           DBOperation = "LIKE"
+	NotLike        DBOperation = "NOT LIKE"
+	// ILike is native on Postgres and rendered as LOWER(field) LIKE LOWER(?) on MySQL.
+	ILike DBOperation = "ILIKE"
+	// IEqual compares case-insensitively, rendered as LOWER(field) = LOWER(?).
+	IEqual    DBOperation = "IEQUAL"
+	IsNull    DBOperation = "IS NULL"
+	IsNotNull DBOperation = "IS NOT NULL"
+	// NullSafeEqual is also true when both sides are NULL. It is rendered as IS NOT DISTINCT FROM
+	// on Postgres.
+	NullSafeEqual DBOperation = "<=>"
+	// Between requires exactly two params, the lower and the upper bound.
+	Between DBOperation = "BETWEEN"
+
+	// Deprecated: use NotIn.
+	NotInt = NotIn
+)
+
+// scalar reports whether operation compares the field with exactly one param.
+func (o DBOperation) scalar() bool {
+	switch o {
+	case NotEqual, Equal, LessOrEqual, LessThan, GreaterOrEqual, GreaterThan, Like, NotLike, ILike, IEqual, NullSafeEqual:
+		return true
+	}
+
+	return false
+}
+
+var (
+	ErrEmptySet       = errors.New("querier: empty SET clause")
+	ErrEmptyIn        = errors.New("querier: empty IN list")
+	ErrParamCount     = errors.New("querier: unexpected number of params")
+	ErrEmptyUsing     = errors.New("querier: empty USING list")
+	ErrEmptyCase      = errors.New("querier: CASE without WHEN")
+	ErrArity          = errors.New("querier: mismatched number of columns")
+	ErrSample         = errors.New("querier: unknown TABLESAMPLE method")
+	ErrNamedParam     = errors.New("querier: named param bound to different values")
+	ErrDuplicateJoin  = errors.New("querier: table joined twice")
+	ErrConflictTarget = errors.New("querier: ON CONFLICT DO UPDATE without conflict columns")
+
+	ErrUnsafeIdentifier = errors.New("querier: unsafe identifier")
+)
+
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	Postgres
 )
 
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z0-9_]+(\.[a-zA-Z0-9_]+)*$`)
+
+// QuoteField validates that name is a plain, optionally qualified, identifier and quotes each of
+// its parts for dialect. Use it for field names that come from user input.
+func QuoteField(dialect Dialect, name string) (DBField, error) {
+	quoted, err := quoteIdentifier(dialect, name)
+	return DBField(quoted), err
+}
+
+// QuoteTable validates and quotes a table name like QuoteField.
+func QuoteTable(dialect Dialect, name string) (DBTable, error) {
+	quoted, err := quoteIdentifier(dialect, name)
+	return DBTable(quoted), err
+}
+
+// SafeTable validates that name, usually computed at runtime such as events_2024_06, is a plain,
+// optionally schema qualified, table name. It is left unquoted, use QuoteTable to quote it too.
+func SafeTable(name string) (DBTable, error) {
+	if !identifierPattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeIdentifier, name)
+	}
+
+	return DBTable(name), nil
+}
+
+func quoteIdentifier(dialect Dialect, name string) (string, error) {
+	if !identifierPattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeIdentifier, name)
+	}
+
+	quote := "`"
+	if dialect == Postgres {
+		quote = `"`
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + part + quote
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
 type Query struct {
-	Table  DBTable
-	fields []DBField
-	where  []string
-	params []any
-	join   []string
+	Table     DBTable
+	operation QueryOperation
+	dialect   Dialect
+
+	placeholderOffset int
+
+	ctes      []string
+	cteParams []any
+	recursive bool
+
+	from         string
+	fromParams   []any
+	sample       string
+	sampleParams []any
+
+	fields      []DBField
+	fieldParams []any
+	valueParams []any
+	distinct    bool
+	distinctOn  []DBField
+	qualify     bool
+	stripPrefix bool
+
+	where      []string
+	params     []any
+	join       []string
+	joinParams []any
+	joined     []joinKey
+	using      []DBTable
+
+	groupBy       []DBField
+	having        []string
+	havingParams  []any
+	orderBy       []string
+	orderByParams []any
 
 	aggregations      []string
 	aggregationParams []any
+	limits            []string
+	limitParams       []any
+
+	lock         string
+	lockModifier string
+
+	returning []DBField
+
+	unions      []string
+	unionParams []any
 
 	sets      []string
+	setExprs  map[int]string
 	setParams []any
+
+	conflict *conflictClause
+
+	restartIdentity bool
+	cascade         bool
+
+	pretty bool
+
+	err error
 }
 
 type QueryBuilderOption func(query *Query)
 
+type conflictClause struct {
+	columns   []DBField
+	doNothing bool
+	update    *Query
+}
+
 func NewQuery(table DBTable, fields []DBField, opts ...QueryBuilderOption) (string, []any) {
-	query := &Query{Table: table, params: make([]any, 0), aggregationParams: make([]any, 0)}
-	for _, opt := range opts {
-		opt(query)
-	}
+	return NewSelect(table, fields, opts...).Build()
+}
+
+// NewQueryE works like NewQuery but reports the problems found while building the query.
+func NewQueryE(table DBTable, fields []DBField, opts ...QueryBuilderOption) (string, []any, error) {
+	return NewSelect(table, fields, opts...).BuildE()
+}
+
+// NewSelect creates a select query that can still be changed with Apply before it is built.
+func NewSelect(table DBTable, fields []DBField, opts ...QueryBuilderOption) *Query {
+	return newQuery(Select, table, fields, opts)
+}
+
+// FromSubquery creates a select query reading from the rows of sub, known as alias in the outer query.
+func FromSubquery(sub *Query, alias string, fields []DBField, opts ...QueryBuilderOption) *Query {
+	query := newQuery(Select, DBTable(alias), fields, nil)
+	from, params := query.subquery(sub)
+	query.from = fmt.Sprintf("(%s) AS %s", from, alias)
+	query.fromParams = params
+
+	return query.Apply(opts...)
+}
+
+func NewInsert(table DBTable, fields []DBField) string {
+	res, _ := NewInsertWith(table, fields)
+	return res
+}
 
-	res := fmt.Sprint(Select)
-	if len(fields) == 0 {
-		res += " *"
+// NewInsertMany renders an insert with rowCount placeholder tuples. The caller binds the
+// values flattened row by row. An empty string is returned when rowCount is lower than 1.
+func NewInsertMany(table DBTable, fields []DBField, rowCount int) string {
+	if rowCount < 1 {
+		return ""
 	}
 
+	values := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ") + ")"
+
+	var b strings.Builder
+	b.Grow(len(Insert) + len(table) + len(fields)*16 + rowCount*(len(values)+2) + 16)
+
+	b.WriteString(string(Insert))
+	b.WriteString(" ")
+	b.WriteString(string(table))
+	b.WriteString(" (")
 	for i, w := range fields {
-		res += " " + string(w)
-		if i != len(fields)-1 {
-			res += ","
+		if i > 0 {
+			b.WriteString(", ")
 		}
+
+		b.WriteString(stripTablePrefix(table, string(w)))
 	}
 
-	res += " FROM"
-	res += fmt.Sprintf(" %s", table)
+	b.WriteString(") VALUES ")
+	for i := range rowCount {
+		if i > 0 {
+			b.WriteString(", ")
+		}
 
-	for _, join := range query.join {
-		res += join
+		b.WriteString(values)
 	}
 
-	resultParams := make([]any, 0, len(query.params)+len(query.aggregations))
-	if len(query.where) > 0 {
-		res += " WHERE "
-		for i, w := range query.where {
-			res += w
+	return b.String()
+}
 
-			if i != len(query.where)-1 {
-				res += " AND "
-			}
-		}
+// NewInsertSelect builds an INSERT INTO table (fields) SELECT ... statement, taking the rows from sub.
+func NewInsertSelect(table DBTable, fields []DBField, sub *Query) (string, []any) {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = stripTablePrefix(table, string(f))
 	}
-	resultParams = append(resultParams, query.params...)
 
-	if len(query.aggregations) > 0 {
-		res += " "
-		for i, ag := range query.aggregations {
-			res += ag
+	query, params := sub.Build()
+	return fmt.Sprintf("%s %s (%s) %s", Insert, table, strings.Join(columns, ", "), query), params
+}
 
-			if i != len(query.aggregations)-1 {
-				res += " "
-			}
-		}
-	}
-	resultParams = append(resultParams, query.aggregationParams...)
+// NewInsertWith works like NewInsert but also accepts options, such as Returning, and returns
+// the params they bind. Without options it renders a single row of placeholders for fields.
+//
+// Without fields the row is inserted with the default value of every column, rendered as
+// DEFAULT VALUES on Postgres and () VALUES () on MySQL. To insert NULL in a field, bind nil to its
+// placeholder.
+func NewInsertWith(table DBTable, fields []DBField, opts ...QueryBuilderOption) (string, []any) {
+	return newQuery(Insert, table, fields, opts).Build()
+}
 
-	return res, query.params
+// NewInsertWithE works like NewInsertWith but reports the problems found while building the query.
+func NewInsertWithE(table DBTable, fields []DBField, opts ...QueryBuilderOption) (string, []any, error) {
+	return newQuery(Insert, table, fields, opts).BuildE()
 }
 
-func NewInsert(table DBTable, fields []DBField) string {
-	res := fmt.Sprintf("%s %s (", Insert, table)
-	values := " VALUES ("
-	for i, w := range fields {
-		res += strings.Replace(string(w), string(table)+".", "", 11)
-		values += "?"
-		if i != len(fields)-1 {
-			res += ", "
-			values += ", "
-		}
-	}
+// NewUpdate builds an UPDATE statement. An update without any Set renders invalid SQL, use
+// NewUpdateE to have it rejected with ErrEmptySet.
+func NewUpdate(table DBTable, opts ...QueryBuilderOption) (string, []any) {
+	return newQuery(Update, table, nil, opts).Build()
+}
+
+// NewUpdateE works like NewUpdate but reports the problems found while building the query.
+func NewUpdateE(table DBTable, opts ...QueryBuilderOption) (string, []any, error) {
+	return newQuery(Update, table, nil, opts).BuildE()
+}
+
+func NewDelete(table DBTable, opts ...QueryBuilderOption) (string, []any) {
+	return newQuery(Delete, table, nil, opts).Build()
+}
 
-	res += ")" + values + ")"
+// NewDeleteE works like NewDelete but reports the problems found while building the query.
+func NewDeleteE(table DBTable, opts ...QueryBuilderOption) (string, []any, error) {
+	return newQuery(Delete, table, nil, opts).BuildE()
+}
 
+// NewTruncate builds a TRUNCATE TABLE statement, see RestartIdentity and Cascade.
+func NewTruncate(table DBTable, opts ...QueryBuilderOption) string {
+	res, _ := newQuery(Truncate, table, nil, opts).Build()
 	return res
 }
 
-func NewUpdate(table DBTable, opts ...QueryBuilderOption) (string, []any) {
-	query := &Query{Table: table, params: make([]any, 0), aggregationParams: make([]any, 0), setParams: make([]any, 0)}
-	for _, opt := range opts {
-		opt(query)
-	}
+// BuildWhere renders only the conditions of opts, joined with AND and without the WHERE keyword,
+// to be added to a handwritten query.
+func BuildWhere(opts ...QueryBuilderOption) (string, []any) {
+	q := newQuery(Select, "", nil, opts)
+	where, params := q.resolveOperators(strings.Join(q.where, " AND "), q.params)
+	return q.rebind(where), params
+}
 
-	res := fmt.Sprint(Update)
-	res += fmt.Sprintf(" %s", table)
+// NewInsertQuery works like NewInsertWith but returns the query instead of rendering it.
+func NewInsertQuery(table DBTable, fields []DBField, opts ...QueryBuilderOption) *Query {
+	return newQuery(Insert, table, fields, opts)
+}
 
-	resultParams := make([]any, 0, len(query.params)+len(query.setParams)+len(query.aggregations))
-	res += " SET"
-	for i, w := range query.sets {
-		res += " " + strings.Replace(string(w), string(table)+".", "", 1) + " = ?"
-		if i != len(query.sets)-1 {
-			res += ","
-		}
+// NewUpdateQuery works like NewUpdate but returns the query instead of rendering it.
+func NewUpdateQuery(table DBTable, opts ...QueryBuilderOption) *Query {
+	return newQuery(Update, table, nil, opts)
+}
+
+// NewDeleteQuery works like NewDelete but returns the query instead of rendering it.
+func NewDeleteQuery(table DBTable, opts ...QueryBuilderOption) *Query {
+	return newQuery(Delete, table, nil, opts)
+}
+
+func newQuery(operation QueryOperation, table DBTable, fields []DBField, opts []QueryBuilderOption) *Query {
+	// most options add a single clause with a single param, so size the common slices for it
+	query := &Query{
+		Table:       table,
+		operation:   operation,
+		fields:      fields,
+		where:       make([]string, 0, len(opts)),
+		params:      make([]any, 0, len(opts)),
+		limitParams: make([]any, 0, 2),
+		setParams:   make([]any, 0),
 	}
-	resultParams = append(resultParams, query.setParams...)
 
-	for _, join := range query.join {
-		res += join
+	return query.Apply(opts...)
+}
+
+// Apply applies opts to the query and returns it.
+func (q *Query) Apply(opts ...QueryBuilderOption) *Query {
+	for _, opt := range opts {
+		opt(q)
 	}
 
-	if len(query.where) > 0 {
-		res += " WHERE "
-		for i, w := range query.where {
-			res += w
-			if i != len(query.where)-1 {
-				res += " AND "
-			}
-		}
+	return q
+}
+
+func (q *Query) Build() (string, []any) {
+	res, params, _ := q.BuildE()
+	return res, params
+}
+
+// BuildE works like Build but reports the problems found while building the query.
+func (q *Query) BuildE() (string, []any, error) {
+	res, params, err := q.build()
+	if err == nil {
+		err = q.validatePlaceholders(res, params)
 	}
-	resultParams = append(resultParams, query.params...)
 
-	if len(query.aggregations) > 0 {
-		res += " "
-		for i, ag := range query.aggregations {
-			res += ag
+	res, params = q.resolveOperators(res, params)
+	return q.rebind(res), params, err
+}
 
-			if i != len(query.aggregations)-1 {
-				res += " "
-			}
+// DebugSQL renders q with its params inlined, to be logged when debugging. Values are escaped on a
+// best effort basis only: never run the result, use Build instead.
+//
+// The placeholders of the inserted fields bound by the caller are left as ?.
+func (q *Query) DebugSQL() string {
+	query, params, _ := q.build()
+	query, params = q.resolveOperators(query, params)
+	skip := q.callerBound()
+	res, _ := replacePlaceholders(query, func(n int) string {
+		if n <= skip || n-skip > len(params) {
+			return "?"
 		}
-	}
-	resultParams = append(resultParams, query.aggregationParams...)
 
-	return res, resultParams
+		return debugLiteral(params[n-skip-1])
+	})
+
+	return res
 }
 
-func NewDelete(table DBTable, opts ...QueryBuilderOption) (string, []any) {
-	query := &Query{Table: table, params: make([]any, 0), aggregationParams: make([]any, 0)}
-	for _, opt := range opts {
-		opt(query)
+func debugLiteral(param any) string {
+	switch v := param.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteString(v)
+	case []byte:
+		return quoteString(string(v))
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case time.Time:
+		return quoteString(v.Format(time.RFC3339Nano))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		return quoteString(fmt.Sprint(v))
 	}
+}
 
-	res := fmt.Sprint(Delete)
-	res += " FROM"
-	res += fmt.Sprintf(" %s", table)
+// validatePlaceholders checks that every ? placeholder of query has a matching param. The values
+// of the inserted fields not added with Value are bound by the caller, so their placeholders are
+// not counted.
+func (q *Query) validatePlaceholders(query string, params []any) error {
+	expected := len(params) + q.callerBound()
 
-	resultParams := make([]any, 0, len(query.params)+len(query.aggregations))
-	for _, join := range query.join {
-		res += join
+	_, n := replacePlaceholders(query, func(int) string { return "?" })
+	if n != expected {
+		return fmt.Errorf("%w: %d placeholders for %d params", ErrParamCount, n, expected)
 	}
 
-	if len(query.where) > 0 {
-		res += " WHERE "
-		for i, w := range query.where {
-			res += w
-			if i != len(query.where)-1 {
-				res += " AND "
-			}
-		}
+	return nil
+}
+
+// Reset clears every clause, param and option of q, keeping its table and operation, so it can
+// be built again without allocating a new query. The backing arrays are kept to be reused, which
+// makes it suited for queries taken from a sync.Pool:
+//
+//	q := pool.Get().(*Query)
+//	defer pool.Put(q)
+//
+//	q.Reset()
+//	query, params := q.Apply(Where(userID, Equal, id)).Build()
+func (q *Query) Reset() {
+	*q = Query{
+		Table:             q.Table,
+		operation:         q.operation,
+		ctes:              q.ctes[:0],
+		cteParams:         q.cteParams[:0],
+		fromParams:        q.fromParams[:0],
+		sampleParams:      q.sampleParams[:0],
+		fields:            q.fields[:0],
+		fieldParams:       q.fieldParams[:0],
+		valueParams:       q.valueParams[:0],
+		distinctOn:        q.distinctOn[:0],
+		where:             q.where[:0],
+		params:            q.params[:0],
+		join:              q.join[:0],
+		joinParams:        q.joinParams[:0],
+		joined:            q.joined[:0],
+		using:             q.using[:0],
+		groupBy:           q.groupBy[:0],
+		having:            q.having[:0],
+		havingParams:      q.havingParams[:0],
+		orderBy:           q.orderBy[:0],
+		orderByParams:     q.orderByParams[:0],
+		aggregations:      q.aggregations[:0],
+		aggregationParams: q.aggregationParams[:0],
+		limits:            q.limits[:0],
+		limitParams:       q.limitParams[:0],
+		returning:         q.returning[:0],
+		unions:            q.unions[:0],
+		unionParams:       q.unionParams[:0],
+		sets:              q.sets[:0],
+		setExprs:          q.setExprs,
+		setParams:         q.setParams[:0],
 	}
-	resultParams = append(resultParams, query.params...)
 
-	if len(query.aggregations) > 0 {
-		res += " "
-		for i, ag := range query.aggregations {
-			res += ag
+	clear(q.setExprs)
+}
 
-			if i != len(query.aggregations)-1 {
-				res += " "
-			}
-		}
+// callerBound returns the number of placeholders of q whose values are bound by the caller instead
+// of q, the ones of the inserted fields not added with Value.
+func (q *Query) callerBound() int {
+	if q.operation != Insert {
+		return 0
 	}
-	resultParams = append(resultParams, query.aggregationParams...)
 
-	return res, query.params
+	return len(q.fields) - len(q.valueParams)
 }
 
-func Where(field DBField, operation DBOperation, params ...any) QueryBuilderOption {
-	return func(q *Query) {
-		where := q.buildWhere(field, operation, params)
-		q.where = append(q.where, where)
+// Clone returns a deep copy of q, so options applied to the copy do not affect q and vice versa.
+func (q *Query) Clone() *Query {
+	clone := *q
+	clone.ctes = slices.Clone(q.ctes)
+	clone.cteParams = slices.Clone(q.cteParams)
+	clone.fromParams = slices.Clone(q.fromParams)
+	clone.sampleParams = slices.Clone(q.sampleParams)
+	clone.fields = slices.Clone(q.fields)
+	clone.fieldParams = slices.Clone(q.fieldParams)
+	clone.valueParams = slices.Clone(q.valueParams)
+	clone.distinctOn = slices.Clone(q.distinctOn)
+	clone.where = slices.Clone(q.where)
+	clone.params = slices.Clone(q.params)
+	clone.join = slices.Clone(q.join)
+	clone.joinParams = slices.Clone(q.joinParams)
+	clone.joined = slices.Clone(q.joined)
+	clone.using = slices.Clone(q.using)
+	clone.groupBy = slices.Clone(q.groupBy)
+	clone.having = slices.Clone(q.having)
+	clone.havingParams = slices.Clone(q.havingParams)
+	clone.orderBy = slices.Clone(q.orderBy)
+	clone.orderByParams = slices.Clone(q.orderByParams)
+	clone.aggregations = slices.Clone(q.aggregations)
+	clone.aggregationParams = slices.Clone(q.aggregationParams)
+	clone.limits = slices.Clone(q.limits)
+	clone.limitParams = slices.Clone(q.limitParams)
+	clone.returning = slices.Clone(q.returning)
+	clone.unions = slices.Clone(q.unions)
+	clone.unionParams = slices.Clone(q.unionParams)
+	clone.sets = slices.Clone(q.sets)
+	clone.setExprs = maps.Clone(q.setExprs)
+	clone.setParams = slices.Clone(q.setParams)
+
+	if q.conflict != nil {
+		conflict := *q.conflict
+		conflict.columns = slices.Clone(q.conflict.columns)
+		conflict.update = q.conflict.update.Clone()
+		clone.conflict = &conflict
 	}
+
+	return &clone
 }
 
-func And(opts ...QueryBuilderOption) QueryBuilderOption {
-	return func(q *Query) {
-		for _, opt := range opts {
-			opt(q)
-		}
+// Count renders a query counting the rows matched by q, ignoring its ordering, limits and locks.
+// Grouped, distinct and union queries, as well as the ones with Raw clauses, are counted as a
+// derived table, so that their resulting rows are counted instead of the rows they read.
+func (q *Query) Count() (string, []any) {
+	count := *q
+	count.orderBy = nil
+	count.orderByParams = nil
+	count.limits = nil
+	count.limitParams = nil
+	count.lock = ""
+	count.lockModifier = ""
+
+	if len(q.groupBy) > 0 || len(q.having) > 0 || q.distinct || len(q.distinctOn) > 0 || len(q.unions) > 0 || len(q.aggregations) > 0 {
+		outer := FromSubquery(&count, "sub", []DBField{Count}, WithDialect(q.dialect), PlaceholderOffset(q.placeholderOffset))
+		outer.pretty = q.pretty
+
+		return outer.Build()
 	}
+
+	count.fields = []DBField{Count}
+	count.fieldParams = nil
+
+	return count.Build()
 }
 
-func Or(opts ...QueryBuilderOption) QueryBuilderOption {
-	return func(q *Query) {
-		temp := &Query{}
-		for _, opt := range opts {
-			opt(temp)
-		}
+func (q *Query) build() (string, []any, error) {
+	var (
+		res    string
+		params []any
+		err    error
+	)
 
-		where := ""
-		for i, w := range temp.where {
-			where += w
-			if i != len(temp.where)-1 {
-				where += " OR "
-			}
-		}
+	switch q.operation {
+	case Insert:
+		res, params, err = q.buildInsert()
+	case Update:
+		res, params, err = q.buildUpdate()
+	case Delete:
+		res, params, err = q.buildDelete()
+	case Truncate:
+		res, params, err = q.buildTruncate()
+	default:
+		res, params, err = q.buildSelect()
+	}
 
-		q.where = append(q.where, where)
-		q.params = append(q.params, temp.params...)
+	if len(q.unions) > 0 {
+		var b strings.Builder
+		b.WriteString("(" + res + ")" + q.separator())
+		writeStrings(&b, q.unions, q.separator())
+		res = b.String()
+		params = append(params, q.unionParams...)
 	}
-}
 
-func Set(field DBField, value any) QueryBuilderOption {
-	return func(q *Query) {
-		q.sets = append(q.sets, string(field))
-		q.setParams = append(q.setParams, value)
+	if len(q.ctes) == 0 {
+		return res, params, err
 	}
-}
 
-func Raw(query string, params ...any) QueryBuilderOption {
-	return func(q *Query) {
-		q.aggregations = append(q.aggregations, query)
-		q.aggregationParams = append(q.aggregationParams, params...)
+	with := "WITH "
+	if q.recursive {
+		with += "RECURSIVE "
 	}
+
+	with += strings.Join(q.ctes, ", ") + q.separator()
+
+	return with + res, append(append(make([]any, 0, len(q.cteParams)+len(params)), q.cteParams...), params...), err
 }
 
-func RawWhere(query string, params ...any) QueryBuilderOption {
-	return func(q *Query) {
-		q.where = append(q.where, query)
-		q.params = append(q.params, params...)
+func (q *Query) buildSelect() (string, []any, error) {
+	var b strings.Builder
+	b.Grow(q.sizeHint())
+
+	b.WriteString(string(Select))
+	switch {
+	case len(q.distinctOn) > 0:
+		b.WriteString(" DISTINCT ON (")
+		writeFields(&b, q.distinctOn, ", ")
+		b.WriteString(")")
+	case q.distinct:
+		b.WriteString(" DISTINCT")
 	}
-}
 
-func Join(table DBTable, joinType JoinType, on, equal DBField) QueryBuilderOption {
-	return func(query *Query) {
-		join := fmt.Sprintf(" %s JOIN %s", joinType, table)
-		if on != "" && equal != "" {
-			join += fmt.Sprintf(" ON %s = %s", on, equal)
-		}
-		query.join = append(query.join, join)
+	if len(q.fields) == 0 {
+		b.WriteString(" *")
 	}
-}
 
-func Limit(limit int) QueryBuilderOption {
-	return func(query *Query) {
-		query.aggregations = append(query.aggregations, "LIMIT ?")
-		query.aggregationParams = append(query.aggregationParams, limit)
+	for i, w := range q.fields {
+		b.WriteString(" ")
+		b.WriteString(q.selectField(w))
+		if i != len(q.fields)-1 {
+			b.WriteString(",")
+		}
 	}
-}
 
-func OrderBy(field DBField, order OrderByType) QueryBuilderOption {
-	return func(query *Query) {
-		query.aggregations = append(query.aggregations, fmt.Sprintf("ORDER BY %s %s", field, order))
+	params := make([]any, 0, len(q.fieldParams)+len(q.fromParams)+len(q.joinParams)+len(q.params)+len(q.havingParams)+len(q.orderByParams)+len(q.aggregationParams)+len(q.limitParams))
+	params = append(params, q.fieldParams...)
+
+	b.WriteString(q.separator())
+	b.WriteString("FROM ")
+	if q.from != "" {
+		b.WriteString(q.from)
+		params = append(params, q.fromParams...)
+	} else {
+		b.WriteString(string(q.Table))
 	}
-}
 
-func First() QueryBuilderOption {
-	return func(query *Query) {
-		query.aggregations = append(query.aggregations, "LIMIT 1")
+	if q.sample != "" {
+		b.WriteString(" ")
+		b.WriteString(q.sample)
+		params = append(params, q.sampleParams...)
 	}
+
+	q.writeJoins(&b)
+	params = append(params, q.joinParams...)
+
+	q.writeWhere(&b)
+	params = append(params, q.params...)
+
+	q.writeGroupBy(&b)
+	q.writeHaving(&b)
+	params = append(params, q.havingParams...)
+
+	q.writeOrderBy(&b)
+	params = append(params, q.orderByParams...)
+
+	q.writeAggregations(&b)
+	params = append(params, q.aggregationParams...)
+	params = append(params, q.limitParams...)
+
+	q.writeLock(&b)
+
+	return b.String(), params, q.err
 }
 
-func (q *Query) buildWhere(field DBField, operation DBOperation, params []any) string {
-	where := fmt.Sprintf("%s %s", field, operation)
+// unqualifiedPattern matches a plain column, optionally aliased, as in name or name AS alias.
+var unqualifiedPattern = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)(?i:\s+AS\s+[a-zA-Z_][a-zA-Z0-9_]*)?$`)
 
-	switch {
-	case len(params) == 1:
-		where += " ?"
-		q.params = append(q.params, params[0])
+// literalKeywords are the bare words of a select list that are values rather than columns.
+var literalKeywords = []string{"NULL", "TRUE", "FALSE", "CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP"}
 
-	case len(params) > 1:
-		where += " ("
+// isUnqualified reports whether field is a plain column of the table, to be qualified by QualifyFields.
+func isUnqualified(field DBField) bool {
+	match := unqualifiedPattern.FindStringSubmatch(string(field))
+	return match != nil && !slices.Contains(literalKeywords, strings.ToUpper(match[1]))
+}
 
-		for i, param := range params {
-			where += "?"
-			q.params = append(q.params, param)
+func (q *Query) selectField(field DBField) string {
+	switch {
+	case q.qualify && isUnqualified(field):
+		return q.qualifier() + "." + string(field)
+	case q.stripPrefix:
+		return stripTablePrefix(DBTable(q.qualifier()), string(field))
+	}
 
-			if i != len(params)-1 {
-				where += ","
-			}
-		}
+	return string(field)
+}
 
-		where += ")"
+// qualifier returns the name the fields of the table are qualified with, its alias when it has one.
+func (q *Query) qualifier() string {
+	table := string(q.Table)
+	if i := strings.LastIndexByte(table, ' '); i >= 0 {
+		return table[i+1:]
 	}
 
-	return where
+	return table
+}
+
+func (q *Query) buildInsert() (string, []any, error) {
+	var b strings.Builder
+	b.Grow(q.sizeHint())
+
+	if len(q.fields) == 0 && q.dialect == Postgres {
+		b.WriteString(fmt.Sprintf("%s %s DEFAULT VALUES", Insert, q.Table))
+	} else {
+		b.WriteString(NewInsertMany(q.Table, q.fields, 1))
+	}
+
+	err := q.err
+	params := make([]any, 0, len(q.valueParams))
+	params = append(params, q.valueParams...)
+	if q.conflict != nil {
+		b.WriteString(" ON CONFLICT")
+		if len(q.conflict.columns) > 0 {
+			b.WriteString(" (")
+			for i, column := range q.conflict.columns {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+
+				b.WriteString(stripTablePrefix(q.Table, string(column)))
+			}
+
+			b.WriteString(")")
+		}
+
+		update := q.conflict.update
+		if q.conflict.doNothing || len(update.sets) == 0 {
+			b.WriteString(" DO NOTHING")
+		} else {
+			if len(q.conflict.columns) == 0 && err == nil {
+				err = fmt.Errorf("%w: INSERT INTO %s", ErrConflictTarget, q.Table)
+			}
+
+			b.WriteString(" DO UPDATE SET ")
+			update.writeSets(&b)
+			params = append(params, update.setParams...)
+
+			update.writeWhere(&b)
+			params = append(params, update.params...)
+		}
+	}
+
+	q.writeReturning(&b)
+
+	return b.String(), params, err
+}
+
+func (q *Query) buildUpdate() (string, []any, error) {
+	err := q.err
+	if len(q.sets) == 0 && err == nil {
+		err = fmt.Errorf("%w: UPDATE %s", ErrEmptySet, q.Table)
+	}
+
+	var b strings.Builder
+	b.Grow(q.sizeHint())
+
+	b.WriteString(string(Update))
+	b.WriteString(" ")
+	b.WriteString(string(q.Table))
+	if len(q.using) > 0 && q.dialect == MySQL {
+		b.WriteString(", ")
+		writeTables(&b, q.using)
+	}
+
+	params := make([]any, 0, len(q.setParams)+len(q.joinParams)+len(q.params)+len(q.orderByParams)+len(q.aggregationParams)+len(q.limitParams))
+	b.WriteString(q.separator())
+	b.WriteString("SET ")
+	q.writeSets(&b)
+	params = append(params, q.setParams...)
+
+	if len(q.using) > 0 && q.dialect == Postgres {
+		b.WriteString(q.separator())
+		b.WriteString("FROM ")
+		writeTables(&b, q.using)
+	}
+
+	q.writeJoins(&b)
+	params = append(params, q.joinParams...)
+
+	q.writeWhere(&b)
+	params = append(params, q.params...)
+
+	q.writeOrderBy(&b)
+	params = append(params, q.orderByParams...)
+
+	q.writeAggregations(&b)
+	params = append(params, q.aggregationParams...)
+	params = append(params, q.limitParams...)
+
+	q.writeReturning(&b)
+
+	return b.String(), params, err
+}
+
+func (q *Query) buildDelete() (string, []any, error) {
+	var b strings.Builder
+	b.Grow(q.sizeHint())
+
+	b.WriteString(string(Delete))
+	switch {
+	case len(q.using) > 0 && q.dialect == Postgres:
+		b.WriteString(" FROM ")
+		b.WriteString(string(q.Table))
+		b.WriteString(" USING ")
+		writeTables(&b, q.using)
+	case len(q.using) > 0:
+		b.WriteString(" ")
+		b.WriteString(string(q.Table))
+		b.WriteString(" FROM ")
+		b.WriteString(string(q.Table))
+		b.WriteString(", ")
+		writeTables(&b, q.using)
+	default:
+		b.WriteString(" FROM ")
+		b.WriteString(string(q.Table))
+	}
+
+	q.writeJoins(&b)
+
+	params := make([]any, 0, len(q.joinParams)+len(q.params)+len(q.orderByParams)+len(q.aggregationParams)+len(q.limitParams))
+	params = append(params, q.joinParams...)
+
+	q.writeWhere(&b)
+	params = append(params, q.params...)
+
+	q.writeOrderBy(&b)
+	params = append(params, q.orderByParams...)
+
+	q.writeAggregations(&b)
+	params = append(params, q.aggregationParams...)
+	params = append(params, q.limitParams...)
+
+	q.writeReturning(&b)
+
+	return b.String(), params, q.err
+}
+
+func (q *Query) buildTruncate() (string, []any, error) {
+	res := fmt.Sprintf("%s %s", Truncate, q.Table)
+	if q.restartIdentity {
+		res += " RESTART IDENTITY"
+	}
+
+	if q.cascade {
+		res += " CASCADE"
+	}
+
+	return res, make([]any, 0), q.err
+}
+
+// sizeHint estimates the length of the rendered query, so it can be built without growing the buffer.
+func (q *Query) sizeHint() int {
+	n := 64 + len(q.Table) + len(q.from)
+	for _, f := range q.fields {
+		n += len(f) + 2
+	}
+
+	for _, clauses := range [][]string{q.where, q.join, q.having, q.orderBy, q.aggregations, q.limits, q.sets} {
+		for _, c := range clauses {
+			n += len(c) + 5
+		}
+	}
+
+	for _, f := range q.groupBy {
+		n += len(f) + 2
+	}
+
+	for _, f := range q.returning {
+		n += len(f) + 2
+	}
+
+	return n
+}
+
+func (q *Query) writeSets(b *strings.Builder) {
+	for i, w := range q.sets {
+		value, ok := q.setExprs[i]
+		if !ok {
+			value = "?"
+		}
+
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		// a MySQL update of several tables needs the qualifier to tell their columns apart
+		if len(q.using) > 0 && q.dialect == MySQL {
+			b.WriteString(w)
+		} else {
+			b.WriteString(stripTablePrefix(q.Table, w))
+		}
+
+		b.WriteString(" = ")
+		b.WriteString(value)
+	}
+}
+
+func (q *Query) writeJoins(b *strings.Builder) {
+	for _, join := range q.join {
+		b.WriteString(q.separator())
+		b.WriteString(join)
+	}
+}
+
+func (q *Query) writeWhere(b *strings.Builder) {
+	if len(q.where) == 0 {
+		return
+	}
+
+	b.WriteString(q.separator())
+	b.WriteString("WHERE ")
+	writeStrings(b, q.where, " AND ")
+}
+
+func (q *Query) writeGroupBy(b *strings.Builder) {
+	if len(q.groupBy) == 0 {
+		return
+	}
+
+	b.WriteString(q.separator())
+	b.WriteString("GROUP BY ")
+	writeFields(b, q.groupBy, ", ")
+}
+
+func (q *Query) writeHaving(b *strings.Builder) {
+	if len(q.having) == 0 {
+		return
+	}
+
+	b.WriteString(q.separator())
+	b.WriteString("HAVING ")
+	writeStrings(b, q.having, " AND ")
+}
+
+func (q *Query) writeOrderBy(b *strings.Builder) {
+	if len(q.orderBy) == 0 {
+		return
+	}
+
+	b.WriteString(q.separator())
+	b.WriteString("ORDER BY ")
+	writeStrings(b, q.orderBy, ", ")
+}
+
+func (q *Query) writeLock(b *strings.Builder) {
+	if q.lock == "" {
+		return
+	}
+
+	b.WriteString(q.separator())
+	b.WriteString(q.lock)
+	if q.lockModifier != "" {
+		b.WriteString(" ")
+		b.WriteString(q.lockModifier)
+	}
+}
+
+func (q *Query) writeReturning(b *strings.Builder) {
+	if len(q.returning) == 0 {
+		return
+	}
+
+	b.WriteString(q.separator())
+	b.WriteString("RETURNING ")
+	writeFields(b, q.returning, ", ")
+}
+
+func (q *Query) writeAggregations(b *strings.Builder) {
+	for _, ag := range q.aggregations {
+		b.WriteString(q.separator())
+		b.WriteString(ag)
+	}
+
+	for _, limit := range q.limits {
+		b.WriteString(q.separator())
+		b.WriteString(limit)
+	}
+}
+
+// separator returns the whitespace put before each clause of the query.
+func (q *Query) separator() string {
+	if q.pretty {
+		return "\n"
+	}
+
+	return " "
+}
+
+// StripPrefix removes the table qualifier from the select fields of the queried table, so the
+// result columns are named plainly. Fields of the other tables are left untouched.
+func StripPrefix() QueryBuilderOption {
+	return func(q *Query) {
+		q.stripPrefix = true
+	}
+}
+
+// TableSample reads only a sample of about percent of the rows of the table (Postgres). method
+// must be SYSTEM, sampling whole pages, or BERNOULLI, sampling each row.
+func TableSample(method string, percent float64) QueryBuilderOption {
+	return func(q *Query) {
+		method := strings.ToUpper(method)
+		if method != "SYSTEM" && method != "BERNOULLI" {
+			q.addError(fmt.Errorf("%w: %q", ErrSample, method))
+			return
+		}
+
+		q.sample = fmt.Sprintf("TABLESAMPLE %s (?)", method)
+		q.sampleParams = []any{percent}
+	}
+}
+
+// PlaceholderOffset numbers the Postgres placeholders after the first offset ones, so that a query
+// or a BuildWhere fragment can be added to a handwritten query already binding offset params.
+func PlaceholderOffset(offset int) QueryBuilderOption {
+	return func(q *Query) {
+		q.placeholderOffset = offset
+	}
+}
+
+// Pretty renders each clause of the query on its own line, which is easier to read in logs.
+func Pretty() QueryBuilderOption {
+	return func(q *Query) {
+		q.pretty = true
+	}
+}
+
+// WithDialect sets the SQL dialect the query is rendered for. MySQL is the default.
+func WithDialect(dialect Dialect) QueryBuilderOption {
+	return func(q *Query) {
+		q.dialect = dialect
+	}
+}
+
+func Where(field DBField, operation DBOperation, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		where := q.buildWhere(field, operation, params)
+		q.where = append(q.where, where)
+	}
+}
+
+// WhereField matches the rows where the comparison between the fields left and right holds, as
+// in users.created_at < users.updated_at.
+func WhereField(left DBField, operation DBOperation, right DBField) QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere(fmt.Sprintf("%s %s %s", left, operation, right), nil)
+	}
+}
+
+// WhereEq matches the rows where each field equals its value in values. The conditions are added
+// in field order so the query is the same for the same map.
+func WhereEq(values map[DBField]any) QueryBuilderOption {
+	return func(q *Query) {
+		for _, field := range slices.Sorted(maps.Keys(values)) {
+			Where(field, Equal, values[field])(q)
+		}
+	}
+}
+
+// Contains matches the rows where field contains substr. Wildcards in substr are escaped.
+func Contains(field DBField, substr string) QueryBuilderOption {
+	return Where(field, Like, "%"+escapeLike(substr)+"%")
+}
+
+// StartsWith matches the rows where field starts with prefix. Wildcards in prefix are escaped.
+func StartsWith(field DBField, prefix string) QueryBuilderOption {
+	return Where(field, Like, escapeLike(prefix)+"%")
+}
+
+// EndsWith matches the rows where field ends with suffix. Wildcards in suffix are escaped.
+func EndsWith(field DBField, suffix string) QueryBuilderOption {
+	return Where(field, Like, "%"+escapeLike(suffix))
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// WhereIn matches the rows where field is one of values.
+func WhereIn[T any](field DBField, values []T) QueryBuilderOption {
+	params := make([]any, len(values))
+	for i, v := range values {
+		params[i] = v
+	}
+
+	return Where(field, In, params...)
+}
+
+// WhereBetween matches the rows where field is between low and high, inclusive.
+func WhereBetween[T any](field DBField, low, high T) QueryBuilderOption {
+	return Where(field, Between, low, high)
+}
+
+// WhereTimeRange matches the rows where field is in the half-open interval [from, to). A zero from
+// or to leaves that side of the interval open.
+func WhereTimeRange(field DBField, from, to time.Time) QueryBuilderOption {
+	return func(q *Query) {
+		var (
+			conditions []string
+			params     []any
+		)
+
+		if !from.IsZero() {
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", field, GreaterOrEqual))
+			params = append(params, from)
+		}
+
+		if !to.IsZero() {
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", field, LessThan))
+			params = append(params, to)
+		}
+
+		q.appendWhere(strings.Join(conditions, " AND "), params)
+	}
+}
+
+// WhereTupleIn matches the rows where the tuple of fields is one of rows, as in
+// (a, b) IN ((?,?),(?,?)). Params are taken row by row.
+func WhereTupleIn(fields []DBField, rows [][]any) QueryBuilderOption {
+	return func(q *Query) {
+		columns := joinFields(fields, ", ")
+		if len(rows) == 0 {
+			q.addError(fmt.Errorf("%w: (%s) %s", ErrEmptyIn, columns, In))
+			q.appendWhere("1=0", nil)
+			return
+		}
+
+		tuples := make([]string, len(rows))
+		params := make([]any, 0, len(rows)*len(fields))
+		for i, row := range rows {
+			if len(row) != len(fields) {
+				q.addError(fmt.Errorf("%w: (%s) %s expects %d params per row, got %d", ErrParamCount, columns, In, len(fields), len(row)))
+			}
+
+			tuples[i] = "(" + placeholders(len(row)) + ")"
+			params = append(params, row...)
+		}
+
+		q.appendWhere(fmt.Sprintf("(%s) %s (%s)", columns, In, strings.Join(tuples, ",")), params)
+	}
+}
+
+// WhereArrayContains matches the rows where the Postgres array field contains all of values.
+func WhereArrayContains(field DBField, values ...any) QueryBuilderOption {
+	return func(q *Query) {
+		if len(values) == 0 {
+			// every array contains the empty one
+			q.appendWhere("1=1", nil)
+			return
+		}
+
+		q.appendWhere(fmt.Sprintf("%s @> ARRAY[%s]", field, placeholders(len(values))), values)
+	}
+}
+
+// WhereArrayAny matches the rows where value is one of the elements of the Postgres array field.
+func WhereArrayAny(field DBField, value any) QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere(fmt.Sprintf("? = ANY(%s)", field), []any{value})
+	}
+}
+
+// WhereIf works like Where but only applies when cond is true.
+func WhereIf(cond bool, field DBField, operation DBOperation, params ...any) QueryBuilderOption {
+	return If(cond, Where(field, operation, params...))
+}
+
+// If applies opt only when cond is true.
+func If(cond bool, opt QueryBuilderOption) QueryBuilderOption {
+	return func(q *Query) {
+		if cond {
+			opt(q)
+		}
+	}
+}
+
+// And groups its conditions, wrapping them in parentheses so they keep their precedence when nested.
+func And(opts ...QueryBuilderOption) QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere(q.group(" AND ", opts))
+	}
+}
+
+// AlwaysTrue adds the 1=1 condition, matching every row.
+func AlwaysTrue() QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere("1=1", nil)
+	}
+}
+
+// AlwaysFalse adds the 1=0 condition, matching no row.
+func AlwaysFalse() QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere("1=0", nil)
+	}
+}
+
+// Not negates its conditions, joined with AND, as in NOT (a = ? AND b = ?).
+func Not(opts ...QueryBuilderOption) QueryBuilderOption {
+	return func(q *Query) {
+		where, params := q.group(" AND ", opts)
+		if where == "" {
+			return
+		}
+
+		if !isWrapped(where) {
+			where = "(" + where + ")"
+		}
+
+		q.appendWhere("NOT "+where, params)
+	}
+}
+
+// Or joins its conditions with OR, wrapping them in parentheses so they keep their precedence when nested.
+func Or(opts ...QueryBuilderOption) QueryBuilderOption {
+	return func(q *Query) {
+		q.appendWhere(q.group(" OR ", opts))
+	}
+}
+
+// OrWhere works like Where but connects the condition to the immediately preceding where clause with
+// OR instead of AND, so Where(a, ...), OrWhere(b, ...), Where(c, ...) renders (a OR b) AND c. Without a
+// preceding clause it works like Where.
+func OrWhere(field DBField, operation DBOperation, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		where := q.buildWhere(field, operation, params)
+		if len(q.where) == 0 {
+			q.where = append(q.where, where)
+			return
+		}
+
+		last := len(q.where) - 1
+		q.where[last] = fmt.Sprintf("(%s OR %s)", q.where[last], where)
+	}
+}
+
+func WhereInQuery(field DBField, sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		query, params := q.subquery(sub)
+		q.appendWhere(fmt.Sprintf("%s IN (%s)", field, query), params)
+	}
+}
+
+// WhereTupleInQuery matches the rows where the tuple of fields is one of the rows returned by sub,
+// as in (a, b) IN (SELECT x, y FROM ...). sub must select as many fields as given.
+func WhereTupleInQuery(fields []DBField, sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		columns := joinFields(fields, ", ")
+		if sub.operation == Select && len(sub.fields) > 0 && len(sub.fields) != len(fields) {
+			q.addError(fmt.Errorf("%w: (%s) %s subquery selecting %d fields", ErrArity, columns, In, len(sub.fields)))
+		}
+
+		query, params := q.subquery(sub)
+		q.appendWhere(fmt.Sprintf("(%s) %s (%s)", columns, In, query), params)
+	}
+}
+
+// WhereAny matches the rows where the comparison between field and any of the rows returned by
+// sub holds, as in field = ANY (SELECT ...).
+func WhereAny(field DBField, operation DBOperation, sub *Query) QueryBuilderOption {
+	return whereQuantified(field, operation, "ANY", sub)
+}
+
+// WhereAll matches the rows where the comparison between field and all of the rows returned by
+// sub holds, as in field > ALL (SELECT ...).
+func WhereAll(field DBField, operation DBOperation, sub *Query) QueryBuilderOption {
+	return whereQuantified(field, operation, "ALL", sub)
+}
+
+func whereQuantified(field DBField, operation DBOperation, quantifier string, sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		query, params := q.subquery(sub)
+		q.appendWhere(fmt.Sprintf("%s %s %s (%s)", field, operation, quantifier, query), params)
+	}
+}
+
+func WhereExists(sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		query, params := q.subquery(sub)
+		q.appendWhere(fmt.Sprintf("EXISTS (%s)", query), params)
+	}
+}
+
+func WhereNotExists(sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		query, params := q.subquery(sub)
+		q.appendWhere(fmt.Sprintf("NOT EXISTS (%s)", query), params)
+	}
+}
+
+// With adds a common table expression named name to the query.
+func With(name string, sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		query, params := q.subquery(sub)
+		q.ctes = append(q.ctes, fmt.Sprintf("%s AS (%s)", name, query))
+		q.cteParams = append(q.cteParams, params...)
+	}
+}
+
+// WithRecursive works like With but renders WITH RECURSIVE, allowing sub to reference name.
+func WithRecursive(name string, sub *Query) QueryBuilderOption {
+	return func(q *Query) {
+		q.recursive = true
+		With(name, sub)(q)
+	}
+}
+
+// Union combines the rows of the query with the distinct rows of other.
+func Union(other *Query) QueryBuilderOption {
+	return union("UNION", other)
+}
+
+// UnionAll combines the rows of the query with all the rows of other.
+func UnionAll(other *Query) QueryBuilderOption {
+	return union("UNION ALL", other)
+}
+
+func union(operator string, other *Query) QueryBuilderOption {
+	return func(q *Query) {
+		query, params := q.subquery(other)
+		q.unions = append(q.unions, fmt.Sprintf("%s (%s)", operator, query))
+		q.unionParams = append(q.unionParams, params...)
+	}
+}
+
+func Set(field DBField, value any) QueryBuilderOption {
+	return func(q *Query) {
+		q.sets = append(q.sets, string(field))
+		q.setParams = append(q.setParams, value)
+	}
+}
+
+// Value inserts value in field, binding it along with the other params of the query so that the
+// columns and their params are always in the same order. Do not mix it with the fields given to
+// the insert, whose values are bound by the caller.
+func Value(field DBField, value any) QueryBuilderOption {
+	return func(q *Query) {
+		q.appendField(field, nil)
+		q.valueParams = append(q.valueParams, value)
+	}
+}
+
+// SetExpr sets field to the raw SQL expression expr, binding its params.
+func SetExpr(field DBField, expr string, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		if q.setExprs == nil {
+			q.setExprs = make(map[int]string)
+		}
+
+		q.setExprs[len(q.sets)] = expr
+		q.sets = append(q.sets, string(field))
+		q.setParams = append(q.setParams, params...)
+	}
+}
+
+// SetExcluded sets field to the value that was proposed for insertion, for use within OnConflict.
+func SetExcluded(field DBField) QueryBuilderOption {
+	return func(q *Query) {
+		SetExpr(field, "EXCLUDED."+stripTablePrefix(q.Table, string(field)))(q)
+	}
+}
+
+// OnConflict turns an insert into an upsert: rows conflicting on columns are updated with the
+// Set options in updates, which may also be filtered with Where. Passing DoNothing, or no Set
+// option at all, skips the conflicting rows instead. Updating requires columns, which are written
+// without the table qualifier.
+func OnConflict(columns []DBField, updates ...QueryBuilderOption) QueryBuilderOption {
+	return func(q *Query) {
+		update := &Query{Table: q.Table, dialect: q.dialect}
+		conflict := &conflictClause{columns: columns, update: update}
+
+		update.conflict = conflict
+		update.Apply(updates...)
+		update.conflict = nil
+		q.addError(update.err)
+
+		q.conflict = conflict
+	}
+}
+
+func DoNothing() QueryBuilderOption {
+	return func(q *Query) {
+		if q.conflict != nil {
+			q.conflict.doNothing = true
+		}
+	}
+}
+
+func Raw(query string, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		q.aggregations = append(q.aggregations, query)
+		q.aggregationParams = append(q.aggregationParams, params...)
+	}
+}
+
+// RawWhere adds the raw SQL condition query, binding its params. The condition is wrapped in
+// parentheses so that any OR within it keeps its precedence, and ignored when empty.
+func RawWhere(query string, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		var where string
+		if strings.TrimSpace(query) != "" {
+			where = "(" + query + ")"
+		}
+
+		q.appendWhere(where, params)
+	}
+}
+
+func Join(table DBTable, joinType JoinType, on, equal DBField) QueryBuilderOption {
+	return JoinAs(table, "", joinType, on, equal)
+}
+
+// JoinAs joins table under alias, which allows joining a table with itself.
+func JoinAs(table DBTable, alias string, joinType JoinType, on, equal DBField) QueryBuilderOption {
+	var conditions []JoinCondition
+	if on != "" && equal != "" {
+		conditions = append(conditions, On(on, equal))
+	}
+
+	return join(table, alias, joinType, conditions)
+}
+
+// JoinOn joins table on all the given conditions.
+func JoinOn(table DBTable, joinType JoinType, conditions ...JoinCondition) QueryBuilderOption {
+	return join(table, "", joinType, conditions)
+}
+
+// JoinUsing joins table on the columns both tables share. At least one column is required.
+func JoinUsing(table DBTable, joinType JoinType, columns ...DBField) QueryBuilderOption {
+	return func(query *Query) {
+		if len(columns) == 0 {
+			query.addError(fmt.Errorf("%w: %s JOIN %s", ErrEmptyUsing, joinType, table))
+			return
+		}
+
+		query.addJoin(joinKey{table: string(table), clause: fmt.Sprintf("%s JOIN %s USING (%s)", joinType, table, joinFields(columns, ", "))})
+	}
+}
+
+// Using lets deletes and updates reference tables, whose join conditions go in the WHERE clause.
+// It renders DELETE ... USING and UPDATE ... FROM on Postgres and multiple-table statements on MySQL,
+// where the SET columns keep their table qualifier.
+func Using(tables ...DBTable) QueryBuilderOption {
+	return func(query *Query) {
+		query.using = append(query.using, tables...)
+	}
+}
+
+type JoinCondition struct {
+	condition string
+	params    []any
+	err       error
+}
+
+func On(field, equal DBField) JoinCondition {
+	return JoinCondition{condition: fmt.Sprintf("%s = %s", field, equal)}
+}
+
+// OnValue is a join condition comparing field to params, like Where does.
+func OnValue(field DBField, operation DBOperation, params ...any) JoinCondition {
+	temp := &Query{}
+	condition, conditionParams := temp.buildCondition(field, operation, params)
+
+	return JoinCondition{condition: condition, params: conditionParams, err: temp.err}
+}
+
+func join(table DBTable, alias string, joinType JoinType, conditions []JoinCondition) QueryBuilderOption {
+	return func(query *Query) {
+		key := joinKey{table: string(table), alias: alias, clause: fmt.Sprintf("%s JOIN %s", joinType, table)}
+		if alias != "" {
+			key.clause += " AS " + alias
+		}
+
+		for i, condition := range conditions {
+			if i == 0 {
+				key.clause += " ON "
+			} else {
+				key.clause += " AND "
+			}
+
+			key.clause += condition.condition
+			key.params = append(key.params, condition.params...)
+			query.addError(condition.err)
+		}
+
+		query.addJoin(key)
+	}
+}
+
+// joinKey identifies a join of the query by the table and alias it joins, along with the clause
+// it rendered and its params.
+type joinKey struct {
+	table  string
+	alias  string
+	clause string
+	params []any
+}
+
+// addJoin adds the join of key to the query. Applying the same join again is ignored, while joining
+// the same table under the same alias in some other way is an error. The conflicting join is still
+// rendered, so that a query built ignoring the error is rejected by the database rather than run
+// without it.
+func (q *Query) addJoin(key joinKey) {
+	for _, joined := range q.joined {
+		if joined.table != key.table || joined.alias != key.alias {
+			continue
+		}
+
+		if joined.clause == key.clause && reflect.DeepEqual(joined.params, key.params) {
+			return
+		}
+
+		q.addError(fmt.Errorf("%w: %s and %s", ErrDuplicateJoin, joined.clause, key.clause))
+		break
+	}
+
+	q.joined = append(q.joined, key)
+	q.join = append(q.join, key.clause)
+	q.joinParams = append(q.joinParams, key.params...)
+}
+
+// SelectRaw adds the raw SQL expression expr, binding its params, to the select list.
+func SelectRaw(expr string, params ...any) QueryBuilderOption {
+	return func(query *Query) {
+		query.appendField(DBField(expr), params)
+	}
+}
+
+// QualifyFields prefixes the unqualified columns of the select list with the table name. Qualified
+// columns and expressions, such as function calls, are left untouched.
+func QualifyFields() QueryBuilderOption {
+	return func(query *Query) {
+		query.qualify = true
+	}
+}
+
+func Distinct() QueryBuilderOption {
+	return func(query *Query) {
+		query.distinct = true
+	}
+}
+
+// DistinctOn renders a Postgres SELECT DISTINCT ON (fields).
+func DistinctOn(fields ...DBField) QueryBuilderOption {
+	return func(query *Query) {
+		query.distinctOn = append(query.distinctOn, fields...)
+	}
+}
+
+func GroupBy(fields ...DBField) QueryBuilderOption {
+	return func(query *Query) {
+		query.groupBy = append(query.groupBy, fields...)
+	}
+}
+
+// GroupByRollup groups by ROLLUP(fields), adding the subtotal rows of each prefix of fields.
+func GroupByRollup(fields ...DBField) QueryBuilderOption {
+	return GroupBy(DBField("ROLLUP(" + joinFields(fields, ", ") + ")"))
+}
+
+func Having(field DBField, operation DBOperation, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		having, havingParams := q.buildCondition(field, operation, params)
+		q.having = append(q.having, having)
+		q.havingParams = append(q.havingParams, havingParams...)
+	}
+}
+
+// HavingRaw adds the raw SQL condition expr, binding its params, to the HAVING clause. Like
+// RawWhere, the condition is wrapped in parentheses.
+func HavingRaw(expr string, params ...any) QueryBuilderOption {
+	return func(q *Query) {
+		q.having = append(q.having, "("+expr+")")
+		q.havingParams = append(q.havingParams, params...)
+	}
+}
+
+func Limit(limit int) QueryBuilderOption {
+	return func(query *Query) {
+		query.limits = append(query.limits, "LIMIT ?")
+		query.limitParams = append(query.limitParams, limit)
+	}
+}
+
+// LimitLiteral works like Limit but writes limit in the query instead of binding it.
+func LimitLiteral(limit int) QueryBuilderOption {
+	return func(query *Query) {
+		query.limits = append(query.limits, "LIMIT "+strconv.Itoa(limit))
+	}
+}
+
+func Offset(offset int) QueryBuilderOption {
+	return func(query *Query) {
+		query.limits = append(query.limits, "OFFSET ?")
+		query.limitParams = append(query.limitParams, offset)
+	}
+}
+
+// LimitOffset limits the query to limit rows, skipping the first offset ones, using the syntax of
+// the dialect the query is built for.
+func LimitOffset(limit, offset int) QueryBuilderOption {
+	return func(query *Query) {
+		query.limits = append(query.limits, limitOffsetMarker)
+		query.limitParams = append(query.limitParams, limit, offset)
+	}
+}
+
+// Paginate limits the query to a single page of pageSize rows. Pages start at 1.
+func Paginate(page, pageSize int) QueryBuilderOption {
+	return func(query *Query) {
+		if page < 1 {
+			page = 1
+		}
+
+		Limit(pageSize)(query)
+		Offset((page - 1) * pageSize)(query)
+	}
+}
+
+// OrderBy adds a sort key. Successive calls are merged into a single ORDER BY clause.
+func OrderBy(field DBField, order OrderByType) QueryBuilderOption {
+	return func(query *Query) {
+		query.orderBy = append(query.orderBy, fmt.Sprintf("%s %s", field, order))
+	}
+}
+
+func OrderByNulls(field DBField, order OrderByType, nulls NullsOrder) QueryBuilderOption {
+	return func(query *Query) {
+		query.orderBy = append(query.orderBy, fmt.Sprintf("%s %s %s", field, order, nulls))
+	}
+}
+
+// OrderByRaw adds the raw SQL expression expr, binding its params, as a sort key.
+func OrderByRaw(expr string, params ...any) QueryBuilderOption {
+	return func(query *Query) {
+		query.orderBy = append(query.orderBy, expr)
+		query.orderByParams = append(query.orderByParams, params...)
+	}
+}
+
+func First() QueryBuilderOption {
+	return func(query *Query) {
+		query.limits = append(query.limits, "LIMIT 1")
+	}
+}
+
+// Returning makes inserts, updates and deletes return the given fields of the affected rows.
+func Returning(fields ...DBField) QueryBuilderOption {
+	return func(query *Query) {
+		query.returning = append(query.returning, fields...)
+	}
+}
+
+// RestartIdentity makes a truncate reset the sequences of the table.
+func RestartIdentity() QueryBuilderOption {
+	return func(query *Query) {
+		query.restartIdentity = true
+	}
+}
+
+// Cascade makes a truncate also truncate the tables referencing the table.
+func Cascade() QueryBuilderOption {
+	return func(query *Query) {
+		query.cascade = true
+	}
+}
+
+func ForUpdate() QueryBuilderOption {
+	return func(query *Query) {
+		query.lock = "FOR UPDATE"
+	}
+}
+
+func ForShare() QueryBuilderOption {
+	return func(query *Query) {
+		query.lock = "FOR SHARE"
+	}
+}
+
+// SkipLocked skips the rows that are already locked instead of waiting for them.
+func SkipLocked() QueryBuilderOption {
+	return func(query *Query) {
+		query.lockModifier = "SKIP LOCKED"
+	}
+}
+
+// NoWait fails right away instead of waiting for locked rows.
+func NoWait() QueryBuilderOption {
+	return func(query *Query) {
+		query.lockModifier = "NOWAIT"
+	}
+}
+
+func (q *Query) buildWhere(field DBField, operation DBOperation, params []any) string {
+	where, whereParams := q.buildCondition(field, operation, params)
+	q.params = append(q.params, whereParams...)
+	return where
+}
+
+func (q *Query) buildCondition(field DBField, operation DBOperation, params []any) (string, []any) {
+	// comparing with NULL is never true, so match the NULL rows instead
+	if len(params) == 1 && isNil(params[0]) {
+		switch operation {
+		case Equal:
+			operation = IsNull
+		case NotEqual:
+			operation = IsNotNull
+		}
+	}
+
+	condition := fmt.Sprintf("%s %s", field, operation)
+	if operation == NullSafeEqual {
+		// the operator depends on the dialect of the query it ends up in, only known once it is built
+		condition = fmt.Sprintf("%s %s", field, nullSafeEqualMarker)
+	}
+
+	isList := operation == In || operation == NotIn
+	if operation.scalar() && len(params) != 1 {
+		q.addError(fmt.Errorf("%w: %s %s expects 1 param, got %d", ErrParamCount, field, operation, len(params)))
+	}
+
+	switch {
+	case operation == IsNull || operation == IsNotNull:
+		return condition, nil
+
+	case operation == IEqual || operation == ILike && q.dialect != Postgres:
+		compare := Like
+		if operation == IEqual {
+			compare = Equal
+		}
+
+		return fmt.Sprintf("LOWER(%s) %s LOWER(?)", field, compare), params
+
+	case operation == Between:
+		if len(params) != 2 {
+			q.addError(fmt.Errorf("%w: %s %s expects 2 params, got %d", ErrParamCount, field, operation, len(params)))
+		}
+
+		condition += " ? AND ?"
+
+	case len(params) == 0 && isList:
+		q.addError(fmt.Errorf("%w: %s %s", ErrEmptyIn, field, operation))
+
+		// nothing is in an empty list, so render a predicate that is still valid SQL
+		if operation == In {
+			return "1=0", nil
+		}
+
+		return "1=1", nil
+
+	case len(params) == 1 && !isList:
+		condition += " ?"
+
+	case len(params) > 0:
+		condition += " (" + placeholders(len(params)) + ")"
+	}
+
+	return condition, params
+}
+
+// isNil reports whether param is bound as NULL, which is also the case of nil pointers.
+func isNil(param any) bool {
+	if param == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(param)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// group applies opts to a separate query and returns its conditions joined by separator along
+// with their params, in placeholder order.
+func (q *Query) group(separator string, opts []QueryBuilderOption) (string, []any) {
+	temp := &Query{Table: q.Table, operation: q.operation, dialect: q.dialect, placeholderOffset: q.placeholderOffset}
+	temp.Apply(opts...)
+	q.addError(temp.err)
+	q.merge(temp)
+
+	where := strings.Join(temp.where, separator)
+	if len(temp.where) > 1 {
+		where = "(" + where + ")"
+	}
+
+	return where, temp.params
+}
+
+// merge adds everything but the where conditions that the opts of a group added to temp, such as a
+// nested Raw or Set, to q along with their params.
+func (q *Query) merge(temp *Query) {
+	q.dialect = temp.dialect
+	if temp.placeholderOffset != 0 {
+		q.placeholderOffset = temp.placeholderOffset
+	}
+
+	q.ctes = append(q.ctes, temp.ctes...)
+	q.cteParams = append(q.cteParams, temp.cteParams...)
+	q.recursive = q.recursive || temp.recursive
+
+	if temp.from != "" {
+		q.from, q.fromParams = temp.from, temp.fromParams
+	}
+
+	if temp.sample != "" {
+		q.sample, q.sampleParams = temp.sample, temp.sampleParams
+	}
+
+	for _, field := range temp.fields {
+		q.appendField(field, nil)
+	}
+
+	q.fieldParams = append(q.fieldParams, temp.fieldParams...)
+	q.valueParams = append(q.valueParams, temp.valueParams...)
+	q.distinct = q.distinct || temp.distinct
+	q.distinctOn = append(q.distinctOn, temp.distinctOn...)
+	q.qualify = q.qualify || temp.qualify
+	q.stripPrefix = q.stripPrefix || temp.stripPrefix
+
+	for _, key := range temp.joined {
+		q.addJoin(key)
+	}
+	q.using = append(q.using, temp.using...)
+
+	q.groupBy = append(q.groupBy, temp.groupBy...)
+	q.having = append(q.having, temp.having...)
+	q.havingParams = append(q.havingParams, temp.havingParams...)
+	q.orderBy = append(q.orderBy, temp.orderBy...)
+	q.orderByParams = append(q.orderByParams, temp.orderByParams...)
+	q.aggregations = append(q.aggregations, temp.aggregations...)
+	q.aggregationParams = append(q.aggregationParams, temp.aggregationParams...)
+	q.limits = append(q.limits, temp.limits...)
+	q.limitParams = append(q.limitParams, temp.limitParams...)
+
+	if temp.lock != "" {
+		q.lock = temp.lock
+	}
+
+	if temp.lockModifier != "" {
+		q.lockModifier = temp.lockModifier
+	}
+
+	q.returning = append(q.returning, temp.returning...)
+	q.unions = append(q.unions, temp.unions...)
+	q.unionParams = append(q.unionParams, temp.unionParams...)
+
+	for i, expr := range temp.setExprs {
+		if q.setExprs == nil {
+			q.setExprs = make(map[int]string)
+		}
+
+		q.setExprs[len(q.sets)+i] = expr
+	}
+
+	q.sets = append(q.sets, temp.sets...)
+	q.setParams = append(q.setParams, temp.setParams...)
+
+	if temp.conflict != nil {
+		q.conflict = temp.conflict
+	}
+
+	q.restartIdentity = q.restartIdentity || temp.restartIdentity
+	q.cascade = q.cascade || temp.cascade
+	q.pretty = q.pretty || temp.pretty
+}
+
+// isWrapped reports whether condition is entirely enclosed in a pair of parentheses.
+func isWrapped(condition string) bool {
+	if !strings.HasPrefix(condition, "(") {
+		return false
+	}
+
+	depth, quoted := 0, false
+	for i, r := range condition {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return i == len(condition)-1
+			}
+		}
+	}
+
+	return false
+}
+
+// subquery renders sub to be embedded in q. Placeholders are left as ? so that they are numbered
+// along with the rest of q.
+func (q *Query) subquery(sub *Query) (string, []any) {
+	query, params, err := sub.build()
+	q.addError(err)
+
+	return query, params
+}
+
+// appendField adds field to the select list without writing to the backing array of the fields
+// slice given by the caller.
+func (q *Query) appendField(field DBField, params []any) {
+	q.fields = append(q.fields[:len(q.fields):len(q.fields)], field)
+	q.fieldParams = append(q.fieldParams, params...)
+}
+
+func (q *Query) appendWhere(where string, params []any) {
+	if where == "" {
+		return
+	}
+
+	q.where = append(q.where, where)
+	q.params = append(q.params, params...)
+}
+
+// stripTablePrefix removes the leading "table." qualifier from field.
+func stripTablePrefix(table DBTable, field string) string {
+	return strings.TrimPrefix(field, string(table)+".")
+}
+
+// placeholders renders n comma separated placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func joinFields(fields []DBField, separator string) string {
+	var b strings.Builder
+	writeFields(&b, fields, separator)
+	return b.String()
+}
+
+func writeTables(b *strings.Builder, tables []DBTable) {
+	for i, table := range tables {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(string(table))
+	}
+}
+
+func writeFields(b *strings.Builder, fields []DBField, separator string) {
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteString(separator)
+		}
+
+		b.WriteString(string(field))
+	}
+}
+
+func writeStrings(b *strings.Builder, values []string, separator string) {
+	for i, value := range values {
+		if i > 0 {
+			b.WriteString(separator)
+		}
+
+		b.WriteString(value)
+	}
+}
+
+// addError records the first error found while building the query.
+func (q *Query) addError(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// rebind replaces the ? placeholders of a rendered statement with the dialect's placeholder
+// style.
+func (q *Query) rebind(query string) string {
+	if q.dialect != Postgres {
+		return query
+	}
+
+	res, _ := replacePlaceholders(query, func(n int) string {
+		return "$" + strconv.Itoa(q.placeholderOffset+n)
+	})
+
+	return res
+}
+
+const (
+	// nullSafeEqualMarker stands for the NullSafeEqual operator until the query is built.
+	nullSafeEqualMarker = "\x00<=>\x00"
+	// limitOffsetMarker stands for the clause of LimitOffset until the query is built. Its params
+	// are bound limit first.
+	limitOffsetMarker = "\x00LIMIT ? OFFSET ?\x00"
+)
+
+// resolveOperators renders the dialect specific operators and clauses of query, with its params,
+// for the dialect of q.
+func (q *Query) resolveOperators(query string, params []any) (string, []any) {
+	operator := "<=>"
+	if q.dialect == Postgres {
+		operator = "IS NOT DISTINCT FROM"
+	}
+
+	query = strings.ReplaceAll(query, nullSafeEqualMarker, operator)
+	if q.dialect != MySQL || !strings.Contains(query, limitOffsetMarker) {
+		return strings.ReplaceAll(query, limitOffsetMarker, "LIMIT ? OFFSET ?"), params
+	}
+
+	// MySQL writes the offset first, so swap the params of each clause
+	params = slices.Clone(params)
+	for i := strings.Index(query, limitOffsetMarker); i >= 0; i = strings.Index(query, limitOffsetMarker) {
+		_, n := replacePlaceholders(query[:i], func(int) string { return "?" })
+		if n -= q.callerBound(); n >= 0 && n+1 < len(params) {
+			params[n], params[n+1] = params[n+1], params[n]
+		}
+
+		query = query[:i] + "LIMIT ?, ?" + query[i+len(limitOffsetMarker):]
+	}
+
+	return query, params
+}
+
+// replacePlaceholders replaces each ? placeholder of query with the result of replace, called with
+// the 1-based position of the placeholder, and returns the number of placeholders found.
+// Question marks inside quoted literals are left untouched.
+func replacePlaceholders(query string, replace func(n int) string) (string, int) {
+	var (
+		res    strings.Builder
+		n      int
+		quoted bool
+	)
+
+	res.Grow(len(query))
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+
+		case r == '?' && !quoted:
+			n++
+			res.WriteString(replace(n))
+			continue
+		}
+
+		res.WriteRune(r)
+	}
+
+	return res.String(), n
 }
@@ -1,7 +1,12 @@
 package querier
 
 import (
+	"flag"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -16,7 +21,7 @@ func userWithID(id int) QueryBuilderOption {
 func setUserName(name string) QueryBuilderOption {
 	return func(query *Query) {
 		query.sets = append(query.sets, "name")
-		query.params = append(query.params, name)
+		query.setParams = append(query.setParams, name)
 	}
 }
 
@@ -44,53 +49,1282 @@ func TestNewQuery(t *testing.T) {
 		require.Equal(t, 1, params[0])
 	})
 
+	t.Run("select with not equal", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, NotEqual, 5))
+		require.Equal(t, "SELECT * FROM users WHERE users.id <> ?", query)
+		require.Equal(t, []any{5}, params)
+	})
+
+	t.Run("select not in ids", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, NotIn, toAnySlice([]int{1, 2})...))
+		require.Equal(t, "SELECT * FROM users WHERE users.id NOT IN (?,?)", query)
+		require.Equal(t, []any{1, 2}, params)
+	})
+
+	t.Run("select in single id", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, In, 1))
+		require.Equal(t, "SELECT * FROM users WHERE users.id IN (?)", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("select null checks", func(t *testing.T) {
+		var userDeletedAt DBField = "users.deleted_at"
+
+		query, params := NewQuery(users, nil, Where(userDeletedAt, IsNull))
+		require.Equal(t, "SELECT * FROM users WHERE users.deleted_at IS NULL", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, nil, Where(userDeletedAt, IsNotNull, "ignored"), Where(userID, Equal, 1))
+		require.Equal(t, "SELECT * FROM users WHERE users.deleted_at IS NOT NULL AND users.id = ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("select between", func(t *testing.T) {
+		var createdAt DBField = "users.created_at"
+
+		query, params := NewQuery(users, nil, Where(createdAt, Between, "2024-01-01", "2024-02-01"), Where(userID, Equal, 1))
+		require.Equal(t, "SELECT * FROM users WHERE users.created_at BETWEEN ? AND ? AND users.id = ?", query)
+		require.Equal(t, []any{"2024-01-01", "2024-02-01", 1}, params)
+	})
+
+	t.Run("select with conditional options", func(t *testing.T) {
+		name := ""
+		query, params := NewQuery(users, nil, WhereIf(name != "", userName, Equal, name), WhereIf(true, userID, Equal, 1), If(false, Limit(10)))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ?", query)
+		require.Equal(t, []any{1}, params)
+
+		name = "bla"
+		query, params = NewQuery(users, nil, WhereIf(name != "", userName, Equal, name), If(true, Limit(10)))
+		require.Equal(t, "SELECT * FROM users WHERE users.name = ? LIMIT ?", query)
+		require.Equal(t, []any{"bla", 10}, params)
+	})
+
+	t.Run("select where in typed slice", func(t *testing.T) {
+		query, params := NewQuery(users, nil, WhereIn(userID, []int{1, 2, 3}))
+		require.Equal(t, "SELECT * FROM users WHERE users.id IN (?,?,?)", query)
+		require.Equal(t, []any{1, 2, 3}, params)
+
+		query, params = NewQuery(users, nil, WhereIn(userName, []string{"a"}), WhereIn(userID, []int64{}))
+		require.Equal(t, "SELECT * FROM users WHERE users.name IN (?) AND 1=0", query)
+		require.Equal(t, []any{"a"}, params)
+	})
+
+	t.Run("select from empty ids", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, In, toAnySlice([]int{})...), Where(userName, Equal, "bla"))
+		require.Equal(t, "SELECT * FROM users WHERE 1=0 AND users.name = ?", query)
+		require.Equal(t, []any{"bla"}, params)
+
+		query, params = NewQuery(users, nil, Where(userID, NotIn, toAnySlice([]int{})...))
+		require.Equal(t, "SELECT * FROM users WHERE 1=1", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("select like helpers", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Contains(userName, "a%b"))
+		require.Equal(t, "SELECT * FROM users WHERE users.name LIKE ?", query)
+		require.Equal(t, []any{`%a\%b%`}, params)
+
+		_, params = NewQuery(users, nil, StartsWith(userName, "a_b"), EndsWith(userName, `c\d`))
+		require.Equal(t, []any{`a\_b%`, `%c\\d`}, params)
+	})
+
+	t.Run("select not like", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userName, NotLike, "%spam%"))
+		require.Equal(t, "SELECT * FROM users WHERE users.name NOT LIKE ?", query)
+		require.Equal(t, []any{"%spam%"}, params)
+	})
+
+	t.Run("select ilike", func(t *testing.T) {
+		query, params := NewQuery(users, nil, WithDialect(Postgres), Where(userName, ILike, "bla%"))
+		require.Equal(t, "SELECT * FROM users WHERE users.name ILIKE $1", query)
+		require.Equal(t, []any{"bla%"}, params)
+
+		query, params = NewQuery(users, nil, Where(userName, ILike, "bla%"))
+		require.Equal(t, "SELECT * FROM users WHERE LOWER(users.name) LIKE LOWER(?)", query)
+		require.Equal(t, []any{"bla%"}, params)
+	})
+
+	t.Run("select null safe equal", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userName, NullSafeEqual, "bla"))
+		require.Equal(t, "SELECT * FROM users WHERE users.name <=> ?", query)
+		require.Equal(t, []any{"bla"}, params)
+
+		query, params = NewQuery(users, nil, WithDialect(Postgres), Where(userName, NullSafeEqual, "bla"))
+		require.Equal(t, "SELECT * FROM users WHERE users.name IS NOT DISTINCT FROM $1", query)
+		require.Equal(t, []any{"bla"}, params)
+
+		query, _ = NewQuery(users, nil, Where(userName, NullSafeEqual, "bla"), WithDialect(Postgres))
+		require.Equal(t, "SELECT * FROM users WHERE users.name IS NOT DISTINCT FROM $1", query)
+
+		query, _ = NewQuery(users, nil, WithDialect(Postgres), JoinOn("products", InnerJoin, OnValue("products.name", NullSafeEqual, "bla")))
+		require.Equal(t, "SELECT * FROM users INNER JOIN products ON products.name IS NOT DISTINCT FROM $1", query)
+
+		flag := Case().When(Where(userName, NullSafeEqual, "bla"), "1").Else("0").As("flag")
+		query, _ = NewQuery(users, nil, WithDialect(Postgres), flag)
+		require.Equal(t, "SELECT CASE WHEN users.name IS NOT DISTINCT FROM $1 THEN 1 ELSE 0 END AS flag FROM users", query)
+
+		query = NewSelect(users, nil, WithDialect(Postgres), Where(userName, NullSafeEqual, "bla")).DebugSQL()
+		require.Equal(t, "SELECT * FROM users WHERE users.name IS NOT DISTINCT FROM 'bla'", query)
+	})
+
+	t.Run("select case insensitive equal", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userName, IEqual, "Bla"))
+		require.Equal(t, "SELECT * FROM users WHERE LOWER(users.name) = LOWER(?)", query)
+		require.Equal(t, []any{"Bla"}, params)
+	})
+
 	t.Run("select specific fields", func(t *testing.T) {
 		query, params := NewQuery(users, []DBField{userID})
 		require.Equal(t, "SELECT users.id FROM users", query)
 		require.Empty(t, params)
 	})
 
-	t.Run("select with condition", func(t *testing.T) {
-		query, params := NewQuery(users, []DBField{userID}, userWithID(123))
-		require.Equal(t, "SELECT users.id FROM users WHERE users.id = ?", query)
-		require.Len(t, params, 1)
-		require.Equal(t, 123, params[0])
+	t.Run("select with condition", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{userID}, userWithID(123))
+		require.Equal(t, "SELECT users.id FROM users WHERE users.id = ?", query)
+		require.Len(t, params, 1)
+		require.Equal(t, 123, params[0])
+	})
+
+	t.Run("select with aggregation", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Limit(1), OrderBy(userID, Desc))
+		require.Equal(t, "SELECT * FROM users ORDER BY users.id DESC LIMIT ?", query)
+		require.Len(t, params, 1)
+		require.Equal(t, 1, params[0])
+	})
+
+	t.Run("select with multiple orders", func(t *testing.T) {
+		query, params := NewQuery(users, nil, OrderBy(userName, ASC), Where(userID, GreaterThan, 1), OrderBy(userID, Desc))
+		require.Equal(t, "SELECT * FROM users WHERE users.id > ? ORDER BY users.name ASC, users.id DESC", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("select with raw order", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, GreaterThan, 1), OrderBy(userID, ASC), OrderByRaw("LOWER(users.name) DESC"), OrderByRaw("FIELD(users.status, ?, ?)", "a", "b"), Limit(10))
+		require.Equal(t, "SELECT * FROM users WHERE users.id > ? ORDER BY users.id ASC, LOWER(users.name) DESC, FIELD(users.status, ?, ?) LIMIT ?", query)
+		require.Equal(t, []any{1, "a", "b", 10}, params)
+	})
+
+	t.Run("select with nulls order", func(t *testing.T) {
+		var userUpdatedAt DBField = "users.updated_at"
+
+		for _, tc := range []struct {
+			order    OrderByType
+			nulls    NullsOrder
+			expected string
+		}{
+			{order: ASC, nulls: NullsFirst, expected: "SELECT * FROM users ORDER BY users.updated_at ASC NULLS FIRST"},
+			{order: ASC, nulls: NullsLast, expected: "SELECT * FROM users ORDER BY users.updated_at ASC NULLS LAST"},
+			{order: Desc, nulls: NullsFirst, expected: "SELECT * FROM users ORDER BY users.updated_at DESC NULLS FIRST"},
+			{order: Desc, nulls: NullsLast, expected: "SELECT * FROM users ORDER BY users.updated_at DESC NULLS LAST"},
+		} {
+			query, params := NewQuery(users, nil, OrderByNulls(userUpdatedAt, tc.order, tc.nulls))
+			require.Equal(t, tc.expected, query)
+			require.Empty(t, params)
+		}
+
+		query, _ := NewQuery(users, nil, OrderByNulls(userUpdatedAt, Desc, NullsLast), OrderBy(userID, ASC))
+		require.Equal(t, "SELECT * FROM users ORDER BY users.updated_at DESC NULLS LAST, users.id ASC", query)
+	})
+
+	t.Run("select with condition and limit", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, Equal, 1), Limit(10))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ? LIMIT ?", query)
+		require.Len(t, params, strings.Count(query, "?"))
+		require.Equal(t, []any{1, 10}, params)
+	})
+
+	t.Run("select with limit and offset", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Limit(20), Offset(40))
+		require.Equal(t, "SELECT * FROM users LIMIT ? OFFSET ?", query)
+		require.Equal(t, []any{20, 40}, params)
+	})
+
+	t.Run("select sample", func(t *testing.T) {
+		query, params := NewQuery("events", nil, WithDialect(Postgres), TableSample("SYSTEM", 1.5), Where("events.type", Equal, "click"), Limit(10))
+		require.Equal(t, "SELECT * FROM events TABLESAMPLE SYSTEM ($1) WHERE events.type = $2 LIMIT $3", query)
+		require.Equal(t, []any{1.5, "click", 10}, params)
+
+		_, _, err := NewQueryE("events", nil, TableSample("SYSTEM; DROP TABLE events", 10))
+		require.ErrorIs(t, err, ErrSample)
+
+		query, params = NewQuery("events", nil, TableSample("SYSTEM (1); DROP TABLE users; --", 1), Where("events.type", Equal, "click"))
+		require.Equal(t, "SELECT * FROM events WHERE events.type = ?", query)
+		require.Equal(t, []any{"click"}, params)
+	})
+
+	t.Run("select with literal limit", func(t *testing.T) {
+		query, params := NewQuery(users, nil, LimitLiteral(100))
+		require.Equal(t, "SELECT * FROM users LIMIT 100", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, nil, WithDialect(Postgres), Where(userID, GreaterThan, 1), LimitLiteral(100), Offset(10))
+		require.Equal(t, "SELECT * FROM users WHERE users.id > $1 LIMIT 100 OFFSET $2", query)
+		require.Equal(t, []any{1, 10}, params)
+	})
+
+	t.Run("select page", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, GreaterThan, 5), Paginate(3, 20))
+		require.Equal(t, "SELECT * FROM users WHERE users.id > ? LIMIT ? OFFSET ?", query)
+		require.Equal(t, []any{5, 20, 40}, params)
+
+		_, params = NewQuery(users, nil, Paginate(0, 20))
+		require.Equal(t, []any{20, 0}, params)
+	})
+
+	t.Run("select distinct", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Distinct())
+		require.Equal(t, "SELECT DISTINCT * FROM users", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, []DBField{userName, userID}, Distinct(), Where(userID, GreaterThan, 1))
+		require.Equal(t, "SELECT DISTINCT users.name, users.id FROM users WHERE users.id > ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("select distinct on", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{userName, userID}, DistinctOn(userName, userID))
+		require.Equal(t, "SELECT DISTINCT ON (users.name, users.id) users.name, users.id FROM users", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("select grouped", func(t *testing.T) {
+		var (
+			orders       DBTable = "orders"
+			ordersUserID DBField = "orders.user_id"
+			ordersStatus DBField = "orders.status"
+		)
+
+		query, params := NewQuery(orders, []DBField{ordersUserID, Count}, Where(ordersStatus, Equal, "paid"), GroupBy(ordersUserID), Limit(10))
+		require.Equal(t, "SELECT orders.user_id, COUNT(*) FROM orders WHERE orders.status = ? GROUP BY orders.user_id LIMIT ?", query)
+		require.Equal(t, []any{"paid", 10}, params)
+
+		query, _ = NewQuery(orders, nil, Limit(10), GroupBy(ordersUserID), GroupBy(ordersStatus))
+		require.Equal(t, "SELECT * FROM orders GROUP BY orders.user_id, orders.status LIMIT ?", query)
+	})
+
+	t.Run("select aliased aggregates", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{As(Count, "total")})
+		require.Equal(t, "SELECT COUNT(*) AS total FROM users", query)
+		require.Empty(t, params)
+
+		query, _ = NewQuery(users, []DBField{As(Sum("users.age"), "sum"), As(Avg("users.age"), "avg"), As(Min("users.age"), "youngest"), As(Max("users.age"), "oldest"), As(CountOf(userID, true), "ids")})
+		require.Equal(t, "SELECT SUM(users.age) AS sum, AVG(users.age) AS avg, MIN(users.age) AS youngest, MAX(users.age) AS oldest, COUNT(DISTINCT users.id) AS ids FROM users", query)
+	})
+
+	t.Run("select null handling expressions", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{As(Coalesce("users.nickname", "users.name", "'anonymous'"), "display"), As(NullIf("users.email", "''"), "email")})
+		require.Equal(t, "SELECT COALESCE(users.nickname, users.name, 'anonymous') AS display, NULLIF(users.email, '') AS email FROM users", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("select grouped with rollup", func(t *testing.T) {
+		var (
+			orders        DBTable = "orders"
+			ordersUserID  DBField = "orders.user_id"
+			ordersStatus  DBField = "orders.status"
+			ordersCountry DBField = "orders.country"
+		)
+
+		query, params := NewQuery(orders, []DBField{ordersCountry, ordersStatus, Count}, Where(ordersUserID, GreaterThan, 10), GroupByRollup(ordersCountry, ordersStatus), Having(Count, GreaterThan, 5))
+		require.Equal(t, "SELECT orders.country, orders.status, COUNT(*) FROM orders WHERE orders.user_id > ? GROUP BY ROLLUP(orders.country, orders.status) HAVING COUNT(*) > ?", query)
+		require.Equal(t, []any{10, 5}, params)
+	})
+
+	t.Run("select grouped with having", func(t *testing.T) {
+		var (
+			orders       DBTable = "orders"
+			ordersUserID DBField = "orders.user_id"
+			ordersStatus DBField = "orders.status"
+		)
+
+		query, params := NewQuery(orders, []DBField{ordersUserID, Count}, Where(ordersStatus, Equal, "paid"), GroupBy(ordersUserID), Having(Count, GreaterThan, 5), Limit(10))
+		require.Equal(t, "SELECT orders.user_id, COUNT(*) FROM orders WHERE orders.status = ? GROUP BY orders.user_id HAVING COUNT(*) > ? LIMIT ?", query)
+		require.Equal(t, []any{"paid", 5, 10}, params)
+	})
+
+	t.Run("select grouped with raw having", func(t *testing.T) {
+		var (
+			orders       DBTable = "orders"
+			ordersUserID DBField = "orders.user_id"
+		)
+
+		query, params := NewQuery(orders, []DBField{ordersUserID}, Where("orders.status", Equal, "paid"), GroupBy(ordersUserID), HavingRaw("SUM(orders.total) > ? AND COUNT(*) > ?", 100, 2), Having(Max("orders.total"), LessThan, 50), Limit(10))
+		require.Equal(t, "SELECT orders.user_id FROM orders WHERE orders.status = ? GROUP BY orders.user_id HAVING (SUM(orders.total) > ? AND COUNT(*) > ?) AND MAX(orders.total) < ? LIMIT ?", query)
+		require.Equal(t, []any{"paid", 100, 2, 50, 10}, params)
+	})
+
+	t.Run("select aggregates", func(t *testing.T) {
+		var (
+			orders       DBTable = "orders"
+			ordersUserID DBField = "orders.user_id"
+			ordersTotal  DBField = "orders.total"
+		)
+
+		query, params := NewQuery(orders, []DBField{Sum(ordersTotal)}, GroupBy(ordersUserID))
+		require.Equal(t, "SELECT SUM(orders.total) FROM orders GROUP BY orders.user_id", query)
+		require.Empty(t, params)
+
+		query, _ = NewQuery(orders, []DBField{ordersUserID, Avg(ordersTotal), Min(ordersTotal), Max(ordersTotal)}, GroupBy(ordersUserID))
+		require.Equal(t, "SELECT orders.user_id, AVG(orders.total), MIN(orders.total), MAX(orders.total) FROM orders GROUP BY orders.user_id", query)
+	})
+
+	t.Run("select count of column", func(t *testing.T) {
+		var userEmail DBField = "users.email"
+
+		query, params := NewQuery(users, []DBField{CountOf(userEmail, false)})
+		require.Equal(t, "SELECT COUNT(users.email) FROM users", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, []DBField{userName, CountOf(userEmail, true)}, GroupBy(userName))
+		require.Equal(t, "SELECT users.name, COUNT(DISTINCT users.email) FROM users GROUP BY users.name", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("select for update", func(t *testing.T) {
+		query, params := NewQuery(users, nil, ForUpdate(), Where(userID, Equal, 1), Limit(10), Offset(20))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ? LIMIT ? OFFSET ? FOR UPDATE", query)
+		require.Equal(t, []any{1, 10, 20}, params)
+	})
+
+	t.Run("select for share with modifiers", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, Equal, 1), ForShare(), NoWait())
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ? FOR SHARE NOWAIT", query)
+		require.Equal(t, []any{1}, params)
+
+		query, params = NewQuery(users, nil, ForUpdate(), SkipLocked(), Limit(5))
+		require.Equal(t, "SELECT * FROM users LIMIT ? FOR UPDATE SKIP LOCKED", query)
+		require.Equal(t, []any{5}, params)
+	})
+
+	t.Run("select qualified fields", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{"name", "email AS contact", userID, "products.name", Count, Sum("score"), "*"}, QualifyFields(), Join(products, InnerJoin, userID, productsUserID))
+		require.Equal(t, "SELECT users.name, users.email AS contact, users.id, products.name, COUNT(*), SUM(score), * FROM users INNER JOIN products ON users.id = products.user_id", query)
+		require.Empty(t, params)
+
+		flag := Case().When(Where("status", Equal, "a"), "1").Else("0").As("flag")
+		query, params = NewQuery(users, []DBField{"name", "NULL AS missing", "id as uid"}, flag, QualifyFields())
+		require.Equal(t, "SELECT users.name, NULL AS missing, users.id as uid, CASE WHEN status = ? THEN 1 ELSE 0 END AS flag FROM users", query)
+		require.Equal(t, []any{"a"}, params)
+	})
+
+	t.Run("select raw field", func(t *testing.T) {
+		var products DBTable = "products"
+
+		query, params := NewQuery(products, []DBField{"*"}, Where("products.price", GreaterThan, 10), SelectRaw("(products.price * ?) AS with_tax", 1.2), Limit(5))
+		require.Equal(t, "SELECT *, (products.price * ?) AS with_tax FROM products WHERE products.price > ? LIMIT ?", query)
+		require.Equal(t, []any{1.2, 10, 5}, params)
+	})
+
+	t.Run("select case", func(t *testing.T) {
+		var userStatus DBField = "users.status"
+
+		flag := Case().When(Where(userStatus, Equal, "a"), "1").When(Where(userStatus, In, "b", "c"), "2").Else("0").As("flag")
+		query, params := NewQuery(users, []DBField{userID}, flag, Where(userID, GreaterThan, 10))
+		require.Equal(t, "SELECT users.id, CASE WHEN users.status = ? THEN 1 WHEN users.status IN (?,?) THEN 2 ELSE 0 END AS flag FROM users WHERE users.id > ?", query)
+		require.Equal(t, []any{"a", "b", "c", 10}, params)
+	})
+
+	t.Run("select case without when", func(t *testing.T) {
+		_, _, err := NewSelect(users, []DBField{userID}, Case().Else("0").As("flag")).BuildE()
+		require.ErrorIs(t, err, ErrEmptyCase)
+	})
+
+	t.Run("where field comparison", func(t *testing.T) {
+		query, params := NewQuery(users, nil, WhereField("users.created_at", LessThan, "users.updated_at"))
+		require.Equal(t, "SELECT * FROM users WHERE users.created_at < users.updated_at", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("where between", func(t *testing.T) {
+		query, params := NewQuery(users, nil, WhereBetween[int]("users.age", 18, 30))
+		require.Equal(t, "SELECT * FROM users WHERE users.age BETWEEN ? AND ?", query)
+		require.Equal(t, []any{18, 30}, params)
+
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		query, params = NewQuery(users, nil, WhereBetween("users.created_at", from, to))
+		require.Equal(t, "SELECT * FROM users WHERE users.created_at BETWEEN ? AND ?", query)
+		require.Equal(t, []any{from, to}, params)
+	})
+
+	t.Run("where time range", func(t *testing.T) {
+		var createdAt DBField = "users.created_at"
+
+		from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+
+		query, params := NewQuery(users, nil, WhereTimeRange(createdAt, from, to), Where(userID, GreaterThan, 1))
+		require.Equal(t, "SELECT * FROM users WHERE users.created_at >= ? AND users.created_at < ? AND users.id > ?", query)
+		require.Equal(t, []any{from, to, 1}, params)
+
+		query, params = NewQuery(users, nil, WhereTimeRange(createdAt, from, time.Time{}))
+		require.Equal(t, "SELECT * FROM users WHERE users.created_at >= ?", query)
+		require.Equal(t, []any{from}, params)
+
+		query, params = NewQuery(users, nil, WhereTimeRange(createdAt, time.Time{}, to))
+		require.Equal(t, "SELECT * FROM users WHERE users.created_at < ?", query)
+		require.Equal(t, []any{to}, params)
+
+		query, params = NewQuery(users, nil, WhereTimeRange(createdAt, time.Time{}, time.Time{}))
+		require.Equal(t, "SELECT * FROM users", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("where equal map", func(t *testing.T) {
+		filters := map[DBField]any{"users.status": "active", "users.age": 30, "users.deleted_at": nil, "users.id": 1}
+		for range 10 {
+			query, params := NewQuery(users, nil, WhereEq(filters))
+			require.Equal(t, "SELECT * FROM users WHERE users.age = ? AND users.deleted_at IS NULL AND users.id = ? AND users.status = ?", query)
+			require.Equal(t, []any{30, 1, "active"}, params)
+		}
+	})
+
+	t.Run("select without table prefix", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{"users.name", "users.email", "orders.total"}, StripPrefix(), Join("orders", InnerJoin, userID, "orders.user_id"), Where(userID, Equal, 1))
+		require.Equal(t, "SELECT name, email, orders.total FROM users INNER JOIN orders ON users.id = orders.user_id WHERE users.id = ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("select with table alias", func(t *testing.T) {
+		u := Alias(users, "u")
+		query, params := NewQuery(u, []DBField{"u.id", "o.total"}, Join(Alias("orders", "o"), InnerJoin, "u.id", "o.user_id"), Where("u.name", Equal, "bla"))
+		require.Equal(t, "SELECT u.id, o.total FROM users u INNER JOIN orders o ON u.id = o.user_id WHERE u.name = ?", query)
+		require.Equal(t, []any{"bla"}, params)
+
+		query, _ = NewQuery(u, []DBField{"id"}, QualifyFields())
+		require.Equal(t, "SELECT u.id FROM users u", query)
+	})
+
+	t.Run("select window function", func(t *testing.T) {
+		var (
+			orders    DBTable = "orders"
+			userID    DBField = "orders.user_id"
+			createdAt DBField = "orders.created_at"
+		)
+
+		rn := Over(RowNumber()).PartitionBy(userID).OrderBy(createdAt, Desc).As("rn")
+		query, params := NewQuery(orders, []DBField{"orders.id", rn}, Where(createdAt, GreaterThan, "2024-01-01"))
+		require.Equal(t, "SELECT orders.id, ROW_NUMBER() OVER (PARTITION BY orders.user_id ORDER BY orders.created_at DESC) AS rn FROM orders WHERE orders.created_at > ?", query)
+		require.Equal(t, []any{"2024-01-01"}, params)
+
+		total := Over(Sum("orders.total")).As("total")
+		query, _ = NewQuery(orders, []DBField{total})
+		require.Equal(t, "SELECT SUM(orders.total) OVER () AS total FROM orders", query)
+	})
+
+	t.Run("select concatenated groups", func(t *testing.T) {
+		var (
+			orders   DBTable = "orders"
+			ordersID DBField = "orders.id"
+		)
+
+		query, params := NewQuery(users, []DBField{userID, GroupConcat(ordersID, ",")}, Join(orders, InnerJoin, userID, "orders.user_id"), GroupBy(userID))
+		require.Equal(t, "SELECT users.id, GROUP_CONCAT(orders.id SEPARATOR ',') FROM users INNER JOIN orders ON users.id = orders.user_id GROUP BY users.id", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, []DBField{userID, StringAgg(ordersID, "', '")}, WithDialect(Postgres), Join(orders, InnerJoin, userID, "orders.user_id"), Where(userID, GreaterThan, 1), GroupBy(userID))
+		require.Equal(t, "SELECT users.id, STRING_AGG(orders.id, ''', ''') FROM users INNER JOIN orders ON users.id = orders.user_id WHERE users.id > $1 GROUP BY users.id", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("join tables without condition", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Join(products, InnerJoin, "", ""))
+		require.Equal(t, "SELECT * FROM users INNER JOIN products", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("join tables with condition", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Join(products, LeftJoin, userID, productsUserID))
+		require.Equal(t, "SELECT * FROM users LEFT JOIN products ON users.id = products.user_id", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("join tables with multiple conditions", func(t *testing.T) {
+		var (
+			userTenantID     DBField = "users.tenant_id"
+			productsTenantID DBField = "products.tenant_id"
+		)
+
+		query, params := NewQuery(users, nil, JoinOn(products, InnerJoin, On(userID, productsUserID), On(userTenantID, productsTenantID)), Where(userID, Equal, 1))
+		require.Equal(t, "SELECT * FROM users INNER JOIN products ON users.id = products.user_id AND users.tenant_id = products.tenant_id WHERE users.id = ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("full join with bound values", func(t *testing.T) {
+		full := JoinOn(products, FullJoin, On(userID, productsUserID), OnValue("products.active", Equal, true))
+
+		query, params := NewQuery(users, nil, WithDialect(Postgres), Where(userID, GreaterThan, 10), full)
+		require.Equal(t, "SELECT * FROM users FULL JOIN products ON users.id = products.user_id AND products.active = $1 WHERE users.id > $2", query)
+		require.Equal(t, []any{true, 10}, params)
+
+		query, params = NewQuery(users, nil, WithDialect(Postgres), full, Where(userID, GreaterThan, 10))
+		require.Equal(t, "SELECT * FROM users FULL JOIN products ON users.id = products.user_id AND products.active = $1 WHERE users.id > $2", query)
+		require.Equal(t, []any{true, 10}, params)
+
+		query, params = NewDelete(users, WithDialect(Postgres), Where(userID, GreaterThan, 10), JoinOn(products, RightJoin, OnValue(productsUserID, Equal, 3)))
+		require.Equal(t, "DELETE FROM users RIGHT JOIN products ON products.user_id = $1 WHERE users.id > $2", query)
+		require.Equal(t, []any{3, 10}, params)
+	})
+
+	t.Run("join tables using columns", func(t *testing.T) {
+		query, params := NewQuery(users, nil, JoinUsing(products, InnerJoin, "user_id"))
+		require.Equal(t, "SELECT * FROM users INNER JOIN products USING (user_id)", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, nil, JoinUsing(products, LeftJoin, "user_id", "tenant_id"))
+		require.Equal(t, "SELECT * FROM users LEFT JOIN products USING (user_id, tenant_id)", query)
+		require.Empty(t, params)
+
+		_, _, err := NewQueryE(users, nil, JoinUsing(products, LeftJoin))
+		require.ErrorIs(t, err, ErrEmptyUsing)
+	})
+
+	t.Run("join tables with bound values", func(t *testing.T) {
+		var productsStatus DBField = "products.status"
+
+		query, params := NewQuery(users, nil,
+			Where(userName, Equal, "bla"),
+			SelectRaw("? AS source", "web"),
+			JoinOn(products, LeftJoin, On(userID, productsUserID), OnValue(productsStatus, In, "active", "pending")),
+			Limit(10),
+		)
+		require.Equal(t, "SELECT ? AS source FROM users LEFT JOIN products ON users.id = products.user_id AND products.status IN (?,?) WHERE users.name = ? LIMIT ?", query)
+		require.Equal(t, []any{"web", "active", "pending", "bla", 10}, params)
+
+		query, params = NewDelete(users, Where(userName, Equal, "bla"), JoinOn(products, InnerJoin, On(userID, productsUserID), OnValue(productsStatus, Equal, "x")))
+		require.Equal(t, "DELETE FROM users INNER JOIN products ON users.id = products.user_id AND products.status = ? WHERE users.name = ?", query)
+		require.Equal(t, []any{"x", "bla"}, params)
+	})
+
+	t.Run("fields from tables", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Join(products, LeftJoin, userID, productsUserID))
+		require.Equal(t, "SELECT * FROM users LEFT JOIN products ON users.id = products.user_id", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("select with alias", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{As(userID, "uid")})
+		require.Equal(t, "SELECT users.id AS uid FROM users", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("self join", func(t *testing.T) {
+		var (
+			userManagerID DBField = "users.manager_id"
+			managerID     DBField = "manager.id"
+			managerName   DBField = "manager.name"
+		)
+
+		query, params := NewQuery(users, []DBField{userName, As(managerName, "manager_name")}, JoinAs(users, "manager", LeftJoin, userManagerID, managerID))
+		require.Equal(t, "SELECT users.name, manager.name AS manager_name FROM users LEFT JOIN users AS manager ON users.manager_id = manager.id", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("join applied twice", func(t *testing.T) {
+		withProducts := JoinOn(products, InnerJoin, On(userID, productsUserID), OnValue("products.active", Equal, true))
+		query, params := NewQuery(users, nil, withProducts, Where(userID, Equal, 1), withProducts, JoinAs(products, "p", LeftJoin, userID, "p.user_id"))
+		require.Equal(t, "SELECT * FROM users INNER JOIN products ON users.id = products.user_id AND products.active = ? LEFT JOIN products AS p ON users.id = p.user_id WHERE users.id = ?", query)
+		require.Equal(t, []any{true, 1}, params)
+	})
+
+	t.Run("join applied at top level and in group", func(t *testing.T) {
+		withProducts := JoinOn(products, InnerJoin, OnValue("products.active", Equal, true))
+		query, params := NewQuery(users, nil, withProducts, Or(withProducts, Where(userID, Equal, 1)))
+		require.Equal(t, "SELECT * FROM users INNER JOIN products ON products.active = ? WHERE users.id = ?", query)
+		require.Equal(t, []any{true, 1}, params)
+	})
+
+	t.Run("same table joined differently", func(t *testing.T) {
+		_, _, err := NewSelect(users, nil, Join(products, InnerJoin, userID, productsUserID), Join(products, LeftJoin, userID, productsUserID)).BuildE()
+		require.ErrorIs(t, err, ErrDuplicateJoin)
+
+		_, _, err = NewSelect(users, nil, JoinOn(products, InnerJoin, OnValue("products.active", Equal, true)), JoinOn(products, InnerJoin, OnValue("products.active", Equal, false))).BuildE()
+		require.ErrorIs(t, err, ErrDuplicateJoin)
+
+		query, _ := NewSelect(users, nil, Join(products, LeftJoin, userID, productsUserID), Join(products, InnerJoin, userID, "products.owner_id")).Build()
+		require.Equal(t, "SELECT * FROM users LEFT JOIN products ON users.id = products.user_id INNER JOIN products ON users.id = products.owner_id", query)
+	})
+
+	t.Run("where with joined tables", func(t *testing.T) {
+		query, params := NewQuery(users, []DBField{userID, productsUserID}, Join(products, RightJoin, userID, productsUserID))
+		require.Equal(t, "SELECT users.id, products.user_id FROM users RIGHT JOIN products ON users.id = products.user_id", query)
+		require.Empty(t, params)
+	})
+}
+
+func TestBuild(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		userID   DBField = "users.id"
+		userName DBField = "users.name"
+	)
+
+	t.Run("build select", func(t *testing.T) {
+		q := NewSelect(users, []DBField{userName}, Where(userID, Equal, 1))
+		q.Apply(Limit(10))
+
+		query, params := q.Build()
+		require.Equal(t, "SELECT users.name FROM users WHERE users.id = ? LIMIT ?", query)
+		require.Equal(t, []any{1, 10}, params)
+
+		again, againParams := q.Build()
+		require.Equal(t, query, again)
+		require.Equal(t, params, againParams)
+	})
+
+	t.Run("apply conditionally", func(t *testing.T) {
+		name := ""
+		q := NewSelect(users, nil, Where(userID, GreaterThan, 10))
+		if name != "" {
+			q.Apply(Where(userName, Equal, name))
+		}
+
+		query, params, err := q.BuildE()
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM users WHERE users.id > ?", query)
+		require.Equal(t, []any{10}, params)
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		base := NewSelect(users, []DBField{userName}, Where(userID, GreaterThan, 10), OrderBy(userName, ASC))
+		base.where = slices.Grow(base.where, 4)
+		base.params = slices.Grow(base.params, 4)
+
+		active := base.Clone().Apply(Where("users.status", Equal, "active"), Limit(5))
+		banned := base.Clone().Apply(Where("users.status", Equal, "banned"), OrderBy(userID, Desc))
+
+		query, params := base.Build()
+		require.Equal(t, "SELECT users.name FROM users WHERE users.id > ? ORDER BY users.name ASC", query)
+		require.Equal(t, []any{10}, params)
+
+		query, params = active.Build()
+		require.Equal(t, "SELECT users.name FROM users WHERE users.id > ? AND users.status = ? ORDER BY users.name ASC LIMIT ?", query)
+		require.Equal(t, []any{10, "active", 5}, params)
+
+		query, params = banned.Build()
+		require.Equal(t, "SELECT users.name FROM users WHERE users.id > ? AND users.status = ? ORDER BY users.name ASC, users.id DESC", query)
+		require.Equal(t, []any{10, "banned"}, params)
+	})
+
+	t.Run("clone upsert", func(t *testing.T) {
+		base := newQuery(Insert, users, []DBField{"name"}, []QueryBuilderOption{OnConflict([]DBField{"name"}, Set("status", "x"))})
+		clone := base.Clone()
+		clone.conflict.update.Apply(Set("age", 1))
+
+		query, params := base.Build()
+		require.Equal(t, "INSERT INTO users (name) VALUES (?) ON CONFLICT (name) DO UPDATE SET status = ?", query)
+		require.Equal(t, []any{"x"}, params)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		q := NewSelect(users, []DBField{userName}, WithDialect(Postgres), Join("products", InnerJoin, userID, "products.user_id"), Where(userID, GreaterThan, 10), GroupBy(userName), OrderBy(userName, ASC), Limit(5), ForUpdate())
+		_, _ = q.Build()
+
+		q.Reset()
+		query, params := q.Apply(Where(userID, Equal, 3)).Build()
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ?", query)
+		require.Equal(t, []any{3}, params)
+
+		q.Reset()
+		query, params = q.Build()
+		require.Equal(t, "SELECT * FROM users", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		q := NewSelect(users, []DBField{userName}, Join("products", InnerJoin, userID, "products.user_id"), Where(userID, GreaterThan, 10), OrderBy(userName, ASC), Paginate(2, 20))
+
+		query, params := q.Count()
+		require.Equal(t, "SELECT COUNT(*) FROM users INNER JOIN products ON users.id = products.user_id WHERE users.id > ?", query)
+		require.Equal(t, []any{10}, params)
+
+		query, params = q.Build()
+		require.Equal(t, "SELECT users.name FROM users INNER JOIN products ON users.id = products.user_id WHERE users.id > ? ORDER BY users.name ASC LIMIT ? OFFSET ?", query)
+		require.Equal(t, []any{10, 20, 20}, params)
+	})
+
+	t.Run("count distinct and unions", func(t *testing.T) {
+		query, params := NewSelect(users, []DBField{userName}, Distinct(), Where(userID, GreaterThan, 10), OrderBy(userName, ASC)).Count()
+		require.Equal(t, "SELECT COUNT(*) FROM (SELECT DISTINCT users.name FROM users WHERE users.id > ?) AS sub", query)
+		require.Equal(t, []any{10}, params)
+
+		query, _ = NewSelect(users, []DBField{userName, userID}, WithDialect(Postgres), DistinctOn(userName)).Count()
+		require.Equal(t, "SELECT COUNT(*) FROM (SELECT DISTINCT ON (users.name) users.name, users.id FROM users) AS sub", query)
+
+		other := NewSelect("admins", []DBField{"admins.name"}, Where("admins.active", Equal, true))
+		query, params = NewSelect(users, []DBField{userName}, Where(userID, GreaterThan, 10), Union(other)).Count()
+		require.Equal(t, "SELECT COUNT(*) FROM ((SELECT users.name FROM users WHERE users.id > ?) UNION (SELECT admins.name FROM admins WHERE admins.active = ?)) AS sub", query)
+		require.Equal(t, []any{10, true}, params)
+
+		query, params = NewSelect(users, []DBField{userID}, Where(userID, GreaterThan, 10), Raw("GROUP BY users.id HAVING COUNT(*) > ?", 2), LimitOffset(10, 20)).Count()
+		require.Equal(t, "SELECT COUNT(*) FROM (SELECT users.id FROM users WHERE users.id > ? GROUP BY users.id HAVING COUNT(*) > ?) AS sub", query)
+		require.Equal(t, []any{10, 2}, params)
+	})
+
+	t.Run("count grouped", func(t *testing.T) {
+		q := NewSelect(users, []DBField{userName, Count}, WithDialect(Postgres), Where(userID, GreaterThan, 10), GroupBy(userName), Having(Count, GreaterThan, 2), OrderBy(userName, ASC), Limit(20))
+
+		query, params := q.Count()
+		require.Equal(t, "SELECT COUNT(*) FROM (SELECT users.name, COUNT(*) FROM users WHERE users.id > $1 GROUP BY users.name HAVING COUNT(*) > $2) AS sub", query)
+		require.Equal(t, []any{10, 2}, params)
+
+		query, params = NewSelect(users, []DBField{userName}, WithDialect(Postgres), Where(userID, GreaterThan, 10), OrderBy(userName, ASC), Limit(20)).Count()
+		require.Equal(t, "SELECT COUNT(*) FROM users WHERE users.id > $1", query)
+		require.Equal(t, []any{10}, params)
+
+		query, _ = q.Clone().Apply(PlaceholderOffset(2)).Count()
+		require.Equal(t, "SELECT COUNT(*) FROM (SELECT users.name, COUNT(*) FROM users WHERE users.id > $3 GROUP BY users.name HAVING COUNT(*) > $4) AS sub", query)
+
+		query, _ = q.Clone().Apply(Pretty()).Count()
+		require.Equal(t, "SELECT COUNT(*)\nFROM (SELECT users.name, COUNT(*)\nFROM users\nWHERE users.id > $1\nGROUP BY users.name\nHAVING COUNT(*) > $2) AS sub", query)
+	})
+
+	t.Run("build reports errors", func(t *testing.T) {
+		_, _, err := NewSelect(users, nil, Where(userID, In)).BuildE()
+		require.ErrorIs(t, err, ErrEmptyIn)
+	})
+}
+
+func TestGroups(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		a DBField = "users.a"
+		b DBField = "users.b"
+		c DBField = "users.c"
+		d DBField = "users.d"
+	)
+
+	t.Run("or group", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(a, Equal, 1), Or(Where(b, Equal, 2), Where(c, Equal, 3)))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ? AND (users.b = ? OR users.c = ?)", query)
+		require.Equal(t, []any{1, 2, 3}, params)
+	})
+
+	t.Run("single condition is not wrapped", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(a, Equal, 1), Or(Where(b, Equal, 2)))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ? AND users.b = ?", query)
+		require.Equal(t, []any{1, 2}, params)
+	})
+
+	t.Run("raw where keeps precedence", func(t *testing.T) {
+		query, params := NewQuery(users, nil, RawWhere("users.a = ? OR users.b = ?", 1, 2), Where(c, Equal, 3))
+		require.Equal(t, "SELECT * FROM users WHERE (users.a = ? OR users.b = ?) AND users.c = ?", query)
+		require.Equal(t, []any{1, 2, 3}, params)
+	})
+
+	t.Run("empty raw where", func(t *testing.T) {
+		query, params := NewQuery(users, nil, RawWhere(""), Where(c, Equal, 3), RawWhere(" "))
+		require.Equal(t, "SELECT * FROM users WHERE users.c = ?", query)
+		require.Equal(t, []any{3}, params)
+	})
+
+	t.Run("or where", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(a, Equal, 1), OrWhere(b, Equal, 2))
+		require.Equal(t, "SELECT * FROM users WHERE (users.a = ? OR users.b = ?)", query)
+		require.Equal(t, []any{1, 2}, params)
+
+		query, params = NewQuery(users, nil, Where(a, Equal, 1), OrWhere(b, Equal, 2), Where(c, Equal, 3))
+		require.Equal(t, "SELECT * FROM users WHERE (users.a = ? OR users.b = ?) AND users.c = ?", query)
+		require.Equal(t, []any{1, 2, 3}, params)
+
+		query, params = NewQuery(users, nil, OrWhere(a, Equal, 1))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("group keeps other clauses", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(a, Equal, 1), Or(Where(b, Equal, 2), Where(c, Equal, 3), Raw("LIMIT ?", 10)), OrderBy(d, ASC))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ? AND (users.b = ? OR users.c = ?) ORDER BY users.d ASC LIMIT ?", query)
+		require.Equal(t, []any{1, 2, 3, 10}, params)
+
+		query, params = NewQuery(users, nil, And(Where(a, Equal, 1), OrderByRaw("FIELD(users.b, ?, ?)", "x", "y")))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ? ORDER BY FIELD(users.b, ?, ?)", query)
+		require.Equal(t, []any{1, "x", "y"}, params)
+	})
+
+	t.Run("group keeps sets and ctes", func(t *testing.T) {
+		query, params, err := NewUpdateE(users, And(Set("users.name", "x"), SetExpr(c, "users.c + ?", 1), Where(a, Equal, 1)), Set(d, 2))
+		require.NoError(t, err)
+		require.Equal(t, "UPDATE users SET name = ?, c = users.c + ?, d = ? WHERE users.a = ?", query)
+		require.Equal(t, []any{"x", 1, 2, 1}, params)
+
+		recent := NewSelect("orders", []DBField{"orders.user_id"}, Where("orders.total", GreaterThan, 10))
+		query, params, err = NewQueryE(users, nil, Or(With("recent", recent), Where(a, Equal, 1), Where(b, Equal, 2)))
+		require.NoError(t, err)
+		require.Equal(t, "WITH recent AS (SELECT orders.user_id FROM orders WHERE orders.total > ?) SELECT * FROM users WHERE (users.a = ? OR users.b = ?)", query)
+		require.Equal(t, []any{10, 1, 2}, params)
+	})
+
+	t.Run("not group", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Not(Or(Where(a, Equal, 1), Where(b, Equal, 2))), Where(c, Equal, 3))
+		require.Equal(t, "SELECT * FROM users WHERE NOT (users.a = ? OR users.b = ?) AND users.c = ?", query)
+		require.Equal(t, []any{1, 2, 3}, params)
+
+		query, params = NewQuery(users, nil, Not(Where(a, Equal, 1), Where(b, Equal, 2)))
+		require.Equal(t, "SELECT * FROM users WHERE NOT (users.a = ? AND users.b = ?)", query)
+		require.Equal(t, []any{1, 2}, params)
+
+		query, _ = NewQuery(users, nil, Not(WhereTupleIn([]DBField{a, b}, [][]any{{1, 2}})))
+		require.Equal(t, "SELECT * FROM users WHERE NOT ((users.a, users.b) IN ((?,?)))", query)
+	})
+
+	t.Run("constant conditions", func(t *testing.T) {
+		query, params := NewQuery(users, nil, AlwaysTrue())
+		require.Equal(t, "SELECT * FROM users WHERE 1=1", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, nil, AlwaysFalse())
+		require.Equal(t, "SELECT * FROM users WHERE 1=0", query)
+		require.Empty(t, params)
+
+		query, params = NewQuery(users, nil, Or(AlwaysFalse(), Where(a, Equal, 1)), And(AlwaysTrue(), Where(b, Equal, 2)))
+		require.Equal(t, "SELECT * FROM users WHERE (1=0 OR users.a = ?) AND (1=1 AND users.b = ?)", query)
+		require.Equal(t, []any{1, 2}, params)
+	})
+
+	t.Run("nil equality", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(a, Equal, nil), Where(b, NotEqual, nil), Where(c, Equal, 3))
+		require.Equal(t, "SELECT * FROM users WHERE users.a IS NULL AND users.b IS NOT NULL AND users.c = ?", query)
+		require.Equal(t, []any{3}, params)
+	})
+
+	t.Run("nil pointer equality", func(t *testing.T) {
+		var (
+			missing *string
+			name    = "bla"
+		)
+
+		query, params := NewQuery(users, nil, Where(a, Equal, missing), Where(b, NotEqual, missing), Where(c, Equal, &name))
+		require.Equal(t, "SELECT * FROM users WHERE users.a IS NULL AND users.b IS NOT NULL AND users.c = ?", query)
+		require.Equal(t, []any{&name}, params)
+	})
+
+	t.Run("and with trailing or", func(t *testing.T) {
+		query, params := NewQuery(users, nil,
+			And(Where(a, Equal, 1), Where(b, In, 2, 3)),
+			Where(c, Equal, 4),
+			Or(Where(d, Between, 5, 6), And(Where(a, Equal, 7), Where(b, Equal, 8))),
+			Limit(9),
+		)
+		require.Equal(t, "SELECT * FROM users WHERE (users.a = ? AND users.b IN (?,?)) AND users.c = ? AND (users.d BETWEEN ? AND ? OR (users.a = ? AND users.b = ?)) LIMIT ?", query)
+		require.Len(t, params, strings.Count(query, "?"))
+		require.Equal(t, []any{1, 2, 3, 4, 5, 6, 7, 8, 9}, params)
+	})
+
+	t.Run("empty group", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Or(), Where(a, Equal, 1))
+		require.Equal(t, "SELECT * FROM users WHERE users.a = ?", query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("nested groups", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Or(And(Where(a, Equal, 1), Where(b, Equal, 2)), And(Where(c, Equal, 3), Or(Where(a, Equal, 4), Where(d, Equal, 5)))))
+		require.Equal(t, "SELECT * FROM users WHERE ((users.a = ? AND users.b = ?) OR (users.c = ? AND (users.a = ? OR users.d = ?)))", query)
+		require.Equal(t, []any{1, 2, 3, 4, 5}, params)
+	})
+}
+
+func TestSubqueries(t *testing.T) {
+	var (
+		users  DBTable = "users"
+		orders DBTable = "orders"
+
+		userID       DBField = "users.id"
+		userStatus   DBField = "users.status"
+		ordersUserID DBField = "orders.user_id"
+		ordersTotal  DBField = "orders.total"
+	)
+
+	t.Run("from subquery", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersUserID, As(Sum(ordersTotal), "total")}, Where(ordersTotal, GreaterThan, 100), GroupBy(ordersUserID))
+		query, params := FromSubquery(sub, "totals", []DBField{"user_id"}, QualifyFields(), Where("totals.total", GreaterThan, 1000), WithDialect(Postgres)).Build()
+		require.Equal(t, "SELECT totals.user_id FROM (SELECT orders.user_id, SUM(orders.total) AS total FROM orders WHERE orders.total > $1 GROUP BY orders.user_id) AS totals WHERE totals.total > $2", query)
+		require.Equal(t, []any{100, 1000}, params)
+	})
+
+	t.Run("where tuple in subquery", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersUserID, ordersTotal}, Where(ordersTotal, GreaterThan, 100))
+		query, params := NewQuery(users, nil, WhereTupleInQuery([]DBField{userID, "users.max_total"}, sub), Where(userStatus, Equal, "active"))
+		require.Equal(t, "SELECT * FROM users WHERE (users.id, users.max_total) IN (SELECT orders.user_id, orders.total FROM orders WHERE orders.total > ?) AND users.status = ?", query)
+		require.Equal(t, []any{100, "active"}, params)
+
+		_, _, err := NewQueryE(users, nil, WhereTupleInQuery([]DBField{userID}, sub))
+		require.ErrorIs(t, err, ErrArity)
+	})
+
+	t.Run("where compared to all or any", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersTotal}, Where(ordersUserID, Equal, 7))
+		query, params := NewQuery(orders, nil, WithDialect(Postgres), Where(ordersUserID, NotEqual, 7), WhereAll(ordersTotal, GreaterThan, sub))
+		require.Equal(t, "SELECT * FROM orders WHERE orders.user_id <> $1 AND orders.total > ALL (SELECT orders.total FROM orders WHERE orders.user_id = $2)", query)
+		require.Equal(t, []any{7, 7}, params)
+
+		query, params = NewQuery(orders, nil, WhereAny(ordersTotal, Equal, sub))
+		require.Equal(t, "SELECT * FROM orders WHERE orders.total = ANY (SELECT orders.total FROM orders WHERE orders.user_id = ?)", query)
+		require.Equal(t, []any{7}, params)
+	})
+
+	t.Run("where in subquery", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersUserID}, Where(ordersTotal, GreaterThan, 100))
+		query, params := NewQuery(users, nil, Where(userStatus, Equal, "active"), WhereInQuery(userID, sub), Limit(10))
+		require.Equal(t, "SELECT * FROM users WHERE users.status = ? AND users.id IN (SELECT orders.user_id FROM orders WHERE orders.total > ?) LIMIT ?", query)
+		require.Equal(t, []any{"active", 100, 10}, params)
+	})
+
+	t.Run("where in subquery with postgres placeholders", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersUserID}, Where(ordersTotal, GreaterThan, 100))
+		query, params := NewQuery(users, nil, WithDialect(Postgres), Where(userStatus, Equal, "active"), WhereInQuery(userID, sub))
+		require.Equal(t, "SELECT * FROM users WHERE users.status = $1 AND users.id IN (SELECT orders.user_id FROM orders WHERE orders.total > $2)", query)
+		require.Equal(t, []any{"active", 100}, params)
 	})
 
-	t.Run("select with aggregation", func(t *testing.T) {
-		query, params := NewQuery(users, nil, Limit(1), OrderBy(userID, Desc))
-		require.Equal(t, "SELECT * FROM users LIMIT ? ORDER BY users.id DESC", query)
-		require.Len(t, params, 0)
+	t.Run("where exists correlated subquery", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{"1"}, RawWhere("orders.user_id = u.id"), Where(ordersTotal, GreaterThan, 100))
+		query, params := NewQuery("users u", []DBField{"u.id"}, Where("u.status", Equal, "active"), WhereExists(sub))
+		require.Equal(t, "SELECT u.id FROM users u WHERE u.status = ? AND EXISTS (SELECT 1 FROM orders WHERE (orders.user_id = u.id) AND orders.total > ?)", query)
+		require.Equal(t, []any{"active", 100}, params)
 	})
 
-	t.Run("join tables without condition", func(t *testing.T) {
-		query, params := NewQuery(users, nil, Join(products, InnerJoin, "", ""))
-		require.Equal(t, "SELECT * FROM users INNER JOIN products", query)
-		require.Empty(t, params)
+	t.Run("where not exists correlated subquery", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{"1"}, RawWhere("orders.user_id = u.id"))
+		query, params := NewQuery("users u", []DBField{"u.id"}, WhereNotExists(sub), Limit(5))
+		require.Equal(t, "SELECT u.id FROM users u WHERE NOT EXISTS (SELECT 1 FROM orders WHERE (orders.user_id = u.id)) LIMIT ?", query)
+		require.Equal(t, []any{5}, params)
 	})
 
-	t.Run("join tables with condition", func(t *testing.T) {
-		query, params := NewQuery(users, nil, Join(products, LeftJoin, userID, productsUserID))
-		require.Equal(t, "SELECT * FROM users LEFT JOIN products ON users.id = products.user_id", query)
-		require.Empty(t, params)
+	t.Run("with cte", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersUserID, As(Sum(ordersTotal), "total")}, Where(ordersTotal, GreaterThan, 10), GroupBy(ordersUserID))
+		query, params := NewQuery("big_spenders", []DBField{"big_spenders.user_id"}, WithDialect(Postgres), With("big_spenders", sub), Where("big_spenders.total", GreaterThan, 1000))
+		require.Equal(t, "WITH big_spenders AS (SELECT orders.user_id, SUM(orders.total) AS total FROM orders WHERE orders.total > $1 GROUP BY orders.user_id) SELECT big_spenders.user_id FROM big_spenders WHERE big_spenders.total > $2", query)
+		require.Equal(t, []any{10, 1000}, params)
 	})
 
-	t.Run("fields from tables", func(t *testing.T) {
-		query, params := NewQuery(users, nil, Join(products, LeftJoin, userID, productsUserID))
-		require.Equal(t, "SELECT * FROM users LEFT JOIN products ON users.id = products.user_id", query)
+	t.Run("with multiple and recursive ctes", func(t *testing.T) {
+		first := NewSelect(users, []DBField{userID}, Where(userStatus, Equal, "active"))
+		second := NewSelect(orders, []DBField{ordersUserID}, Where(ordersTotal, GreaterThan, 5))
+		query, params := NewQuery("a", nil, With("a", first), WithRecursive("b", second), Where("a.id", In, 1, 2))
+		require.Equal(t, "WITH RECURSIVE a AS (SELECT users.id FROM users WHERE users.status = ?), b AS (SELECT orders.user_id FROM orders WHERE orders.total > ?) SELECT * FROM a WHERE a.id IN (?,?)", query)
+		require.Equal(t, []any{"active", 5, 1, 2}, params)
+	})
+
+	t.Run("union", func(t *testing.T) {
+		other := NewSelect(orders, []DBField{ordersUserID}, Where(ordersTotal, GreaterThan, 100))
+		query, params := NewQuery(users, []DBField{userID}, Where(userStatus, Equal, "active"), Union(other))
+		require.Equal(t, "(SELECT users.id FROM users WHERE users.status = ?) UNION (SELECT orders.user_id FROM orders WHERE orders.total > ?)", query)
+		require.Equal(t, []any{"active", 100}, params)
+	})
+
+	t.Run("union all", func(t *testing.T) {
+		other := NewSelect(orders, []DBField{ordersUserID}, Where(ordersTotal, GreaterThan, 100))
+		query, params := NewQuery(users, []DBField{userID}, WithDialect(Postgres), Where(userStatus, Equal, "active"), Limit(5), UnionAll(other), UnionAll(other))
+		require.Equal(t, "(SELECT users.id FROM users WHERE users.status = $1 LIMIT $2) UNION ALL (SELECT orders.user_id FROM orders WHERE orders.total > $3) UNION ALL (SELECT orders.user_id FROM orders WHERE orders.total > $4)", query)
+		require.Equal(t, []any{"active", 5, 100, 100}, params)
+	})
+
+	t.Run("subquery errors are reported", func(t *testing.T) {
+		sub := NewSelect(orders, []DBField{ordersUserID}, Where(ordersTotal, In))
+		_, _, err := NewQueryE(users, nil, WhereInQuery(userID, sub))
+		require.ErrorIs(t, err, ErrEmptyIn)
+	})
+}
+
+func TestDialect(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		userID   DBField = "users.id"
+		userName DBField = "users.name"
+	)
+
+	t.Run("mysql placeholders by default", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, Equal, 1), Limit(10))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ? LIMIT ?", query)
+		require.Equal(t, []any{1, 10}, params)
+	})
+
+	t.Run("postgres select", func(t *testing.T) {
+		query, params := NewQuery(users, nil, WithDialect(Postgres), Where(userID, Equal, 1), Limit(10))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = $1 LIMIT $2", query)
+		require.Equal(t, []any{1, 10}, params)
+	})
+
+	t.Run("postgres update", func(t *testing.T) {
+		query, params := NewUpdate(users, WithDialect(Postgres), Set(userName, "bla"), Where(userID, In, 1, 2))
+		require.Equal(t, "UPDATE users SET name = $1 WHERE users.id IN ($2,$3)", query)
+		require.Equal(t, []any{"bla", 1, 2}, params)
+	})
+
+	t.Run("limit offset", func(t *testing.T) {
+		query, params := NewQuery(users, nil, Where(userID, Equal, 1), LimitOffset(20, 40))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = ? LIMIT ?, ?", query)
+		require.Equal(t, []any{1, 40, 20}, params)
+
+		query, params = NewQuery(users, nil, WithDialect(Postgres), Where(userID, Equal, 1), LimitOffset(20, 40))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = $1 LIMIT $2 OFFSET $3", query)
+		require.Equal(t, []any{1, 20, 40}, params)
+
+		query, params = NewQuery(users, nil, Where(userID, Equal, 1), LimitOffset(20, 40), WithDialect(Postgres))
+		require.Equal(t, "SELECT * FROM users WHERE users.id = $1 LIMIT $2 OFFSET $3", query)
+		require.Equal(t, []any{1, 20, 40}, params)
+	})
+
+	t.Run("limit offset in subquery", func(t *testing.T) {
+		sub := NewSelect("orders", []DBField{"orders.user_id"}, LimitOffset(20, 40))
+
+		query, params := NewQuery(users, nil, WithDialect(Postgres), WhereInQuery(userID, sub), Where(userName, Equal, "bla"))
+		require.Equal(t, "SELECT * FROM users WHERE users.id IN (SELECT orders.user_id FROM orders LIMIT $1 OFFSET $2) AND users.name = $3", query)
+		require.Equal(t, []any{20, 40, "bla"}, params)
+
+		query, params = NewQuery(users, nil, WhereInQuery(userID, sub), Where(userName, Equal, "bla"), LimitOffset(5, 10))
+		require.Equal(t, "SELECT * FROM users WHERE users.id IN (SELECT orders.user_id FROM orders LIMIT ?, ?) AND users.name = ? LIMIT ?, ?", query)
+		require.Equal(t, []any{40, 20, "bla", 10, 5}, params)
+	})
+
+	t.Run("json fields", func(t *testing.T) {
+		var userData DBField = "users.data"
+
+		query, params := NewQuery(users, []DBField{As(JSONGet(userData, "address"), "address")}, WithDialect(Postgres), Where(JSONText(userData, "email"), Equal, "a@b.c"), Where(JSONText(JSONGet(userData, "address"), "city"), Equal, "Rio"))
+		require.Equal(t, "SELECT users.data->'address' AS address FROM users WHERE users.data->>'email' = $1 AND users.data->'address'->>'city' = $2", query)
+		require.Equal(t, []any{"a@b.c", "Rio"}, params)
+	})
+
+	t.Run("array predicates", func(t *testing.T) {
+		var userTags DBField = "users.tags"
+
+		query, params := NewQuery(users, nil, WithDialect(Postgres), Where(userID, GreaterThan, 1), WhereArrayContains(userTags, "go", "sql"))
+		require.Equal(t, "SELECT * FROM users WHERE users.id > $1 AND users.tags @> ARRAY[$2,$3]", query)
+		require.Len(t, params, 3)
+		require.Equal(t, []any{1, "go", "sql"}, params)
+
+		query, params = NewQuery(users, nil, WithDialect(Postgres), WhereArrayAny(userTags, "go"), Where(userID, GreaterThan, 1))
+		require.Equal(t, "SELECT * FROM users WHERE $1 = ANY(users.tags) AND users.id > $2", query)
+		require.Len(t, params, 2)
+		require.Equal(t, []any{"go", 1}, params)
+
+		query, params = NewQuery(users, nil, WithDialect(Postgres), WhereArrayContains(userTags))
+		require.Equal(t, "SELECT * FROM users WHERE 1=1", query)
 		require.Empty(t, params)
 	})
 
-	t.Run("where with joined tables", func(t *testing.T) {
-		query, params := NewQuery(users, []DBField{userID, productsUserID}, Join(products, RightJoin, userID, productsUserID))
-		require.Equal(t, "SELECT users.id, products.user_id FROM users RIGHT JOIN products ON users.id = products.user_id", query)
+	t.Run("postgres delete keeps quoted question marks", func(t *testing.T) {
+		query, params := NewDelete(users, WithDialect(Postgres), RawWhere("users.name <> '?'"), Where(userID, Equal, 1))
+		require.Equal(t, "DELETE FROM users WHERE (users.name <> '?') AND users.id = $1", query)
+		require.Equal(t, []any{1}, params)
+	})
+}
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestPretty(t *testing.T) {
+	var (
+		users  DBTable = "users"
+		orders DBTable = "orders"
+
+		userID      DBField = "users.id"
+		userName    DBField = "users.name"
+		ordersTotal DBField = "orders.total"
+	)
+
+	query, params := NewQuery(users, []DBField{userID, userName, Sum(ordersTotal)},
+		Pretty(),
+		Join(orders, InnerJoin, userID, "orders.user_id"),
+		Where(userName, Like, "a%"),
+		Where(ordersTotal, GreaterThan, 10),
+		GroupBy(userID, userName),
+		OrderBy(userName, ASC),
+		Limit(10),
+		UnionAll(NewSelect(users, []DBField{userID, userName, "0"}, Where(userName, Like, "b%"))),
+	)
+	require.Equal(t, []any{"a%", 10, 10, "b%"}, params)
+
+	golden := "testdata/pretty_select.golden"
+	if *update {
+		require.NoError(t, os.WriteFile(golden, []byte(query), 0o644))
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), query)
+}
+
+func TestBuildWhere(t *testing.T) {
+	var (
+		userID   DBField = "users.id"
+		userName DBField = "users.name"
+	)
+
+	where, params := BuildWhere(Where(userID, GreaterThan, 10), Where(userName, In, "a", "b"))
+	require.Equal(t, "users.id > ? AND users.name IN (?,?)", where)
+	require.Equal(t, []any{10, "a", "b"}, params)
+
+	where, params = BuildWhere(WithDialect(Postgres), Where(userID, GreaterThan, 10), Where(userName, Equal, "a"))
+	require.Equal(t, "users.id > $1 AND users.name = $2", where)
+	require.Equal(t, []any{10, "a"}, params)
+
+	where, params = BuildWhere(WithDialect(Postgres), PlaceholderOffset(2), Where(userID, GreaterThan, 10), Where(userName, Equal, "a"))
+	require.Equal(t, "users.id > $3 AND users.name = $4", where)
+	require.Equal(t, []any{10, "a"}, params)
+
+	where, params = BuildWhere()
+	require.Empty(t, where)
+	require.Empty(t, params)
+}
+
+func TestDebugSQL(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		name    DBField = "users.name"
+		age     DBField = "users.age"
+		deleted DBField = "users.deleted_at"
+	)
+
+	t.Run("inlines params", func(t *testing.T) {
+		query := NewSelect(users, []DBField{name}, Where(name, Equal, "o'neil"), Where(age, GreaterThan, 18), Where(deleted, Equal, nil), Limit(10))
+		require.Equal(t, "SELECT users.name FROM users WHERE users.name = 'o''neil' AND users.age > 18 AND users.deleted_at IS NULL LIMIT 10", query.DebugSQL())
+	})
+
+	t.Run("nil param", func(t *testing.T) {
+		query := NewSelect(users, []DBField{name}, Where(name, In, "a", nil))
+		require.Equal(t, "SELECT users.name FROM users WHERE users.name IN ('a',NULL)", query.DebugSQL())
+	})
+
+	t.Run("insert with caller bound fields", func(t *testing.T) {
+		query := NewInsertQuery(users, []DBField{"name"}, OnConflict([]DBField{"name"}, Set("status", "v")))
+		require.Equal(t, "INSERT INTO users (name) VALUES (?) ON CONFLICT (name) DO UPDATE SET status = 'v'", query.DebugSQL())
+
+		query = NewInsertQuery(users, nil, Value("name", "bla"), Value("age", 3))
+		require.Equal(t, "INSERT INTO users (name, age) VALUES ('bla', 3)", query.DebugSQL())
+	})
+
+	t.Run("question marks in values", func(t *testing.T) {
+		query := NewSelect(users, []DBField{name}, Where(name, Equal, "?"), Where(age, Equal, 1), WithDialect(Postgres))
+		require.Equal(t, "SELECT users.name FROM users WHERE users.name = '?' AND users.age = 1", query.DebugSQL())
+	})
+}
+
+func TestBuildErrors(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		userID    DBField = "users.id"
+		userName  DBField = "users.name"
+		createdAt DBField = "users.created_at"
+	)
+
+	t.Run("valid query", func(t *testing.T) {
+		query, params, err := NewQueryE(users, nil, Where(userID, In, 1, 2))
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM users WHERE users.id IN (?,?)", query)
+		require.Equal(t, []any{1, 2}, params)
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		_, _, err := NewUpdateE(users, Where(userID, Equal, 1))
+		require.ErrorIs(t, err, ErrEmptySet)
+		require.ErrorContains(t, err, "UPDATE users")
+
+		name, age := "", 0
+		_, _, err = NewUpdateE(users, If(name != "", Set("name", name)), If(age > 0, Set("age", age)), Where(userID, Equal, 1))
+		require.ErrorIs(t, err, ErrEmptySet)
+	})
+
+	t.Run("empty in list", func(t *testing.T) {
+		query, params, err := NewDeleteE(users, Where(userID, In))
+		require.ErrorIs(t, err, ErrEmptyIn)
+		require.ErrorContains(t, err, "users.id IN")
+		require.Equal(t, "DELETE FROM users WHERE 1=0", query)
 		require.Empty(t, params)
 	})
+
+	t.Run("empty in list inside or", func(t *testing.T) {
+		_, _, err := NewQueryE(users, nil, Or(Where(userName, Equal, "bla"), Where(userID, NotIn)))
+		require.ErrorIs(t, err, ErrEmptyIn)
+	})
+
+	t.Run("placeholder and param mismatch", func(t *testing.T) {
+		query, params, err := NewQueryE(users, nil, RawWhere("users.a = ? AND users.b = ?", 1))
+		require.ErrorIs(t, err, ErrParamCount)
+		require.ErrorContains(t, err, "2 placeholders for 1 params")
+		require.Equal(t, "SELECT * FROM users WHERE (users.a = ? AND users.b = ?)", query)
+		require.Equal(t, []any{1}, params)
+
+		_, _, err = NewQueryE(users, nil, Raw("LIMIT 10", 1))
+		require.ErrorIs(t, err, ErrParamCount)
+
+		_, _, err = NewUpdateE(users, setUserName("bla"), userWithID(1), RawWhere("users.name <> '?'"))
+		require.NoError(t, err)
+	})
+
+	t.Run("unbalanced between", func(t *testing.T) {
+		_, _, err := NewQueryE(users, nil, Where(createdAt, Between, "2024-01-01"))
+		require.ErrorIs(t, err, ErrParamCount)
+		require.ErrorContains(t, err, "users.created_at BETWEEN")
+	})
+
+	t.Run("unbalanced scalar operation", func(t *testing.T) {
+		_, _, err := NewQueryE(users, nil, Where(userID, Equal))
+		require.ErrorIs(t, err, ErrParamCount)
+		require.ErrorContains(t, err, "users.id = expects 1 param, got 0")
+
+		_, _, err = NewQueryE(users, nil, Where(userID, GreaterThan, 1, 2))
+		require.ErrorIs(t, err, ErrParamCount)
+		require.ErrorContains(t, err, "users.id > expects 1 param, got 2")
+	})
+}
+
+func TestQuoteIdentifiers(t *testing.T) {
+	t.Run("quote field", func(t *testing.T) {
+		field, err := QuoteField(MySQL, "users.name")
+		require.NoError(t, err)
+		require.Equal(t, DBField("`users`.`name`"), field)
+
+		field, err = QuoteField(Postgres, "name")
+		require.NoError(t, err)
+		require.Equal(t, DBField(`"name"`), field)
+	})
+
+	t.Run("quote table", func(t *testing.T) {
+		table, err := QuoteTable(Postgres, "users")
+		require.NoError(t, err)
+
+		field, err := QuoteField(Postgres, "users.id")
+		require.NoError(t, err)
+
+		query, params := NewQuery(table, []DBField{field}, WithDialect(Postgres), Where(field, Equal, 1))
+		require.Equal(t, `SELECT "users"."id" FROM "users" WHERE "users"."id" = $1`, query)
+		require.Equal(t, []any{1}, params)
+	})
+
+	t.Run("safe table", func(t *testing.T) {
+		table, err := SafeTable("events_2024_06")
+		require.NoError(t, err)
+		require.Equal(t, DBTable("events_2024_06"), table)
+
+		for _, name := range []string{"events 2024", "events;", "events; DROP TABLE users", ""} {
+			_, err := SafeTable(name)
+			require.ErrorIs(t, err, ErrUnsafeIdentifier, name)
+		}
+	})
+
+	t.Run("reject unsafe identifiers", func(t *testing.T) {
+		for _, name := range []string{"", "users name", "name;", "name; DROP TABLE users", "users..name", "name`", `na"me`, "COUNT(*)"} {
+			_, err := QuoteField(MySQL, name)
+			require.ErrorIs(t, err, ErrUnsafeIdentifier, name)
+
+			_, err = QuoteTable(Postgres, name)
+			require.ErrorIs(t, err, ErrUnsafeIdentifier, name)
+		}
+	})
 }
 
 func TestNewDelete(t *testing.T) {
 	var (
 		users DBTable = "users"
+
+		status DBField = "users.status"
 	)
 
 	t.Run("delete rows", func(t *testing.T) {
@@ -99,6 +1333,52 @@ func TestNewDelete(t *testing.T) {
 		require.Len(t, params, 1)
 		require.Equal(t, 123, params[0])
 	})
+
+	t.Run("delete rows with limit", func(t *testing.T) {
+		query, params := NewDelete(users, Where(status, Equal, "x"), Limit(100))
+		require.Equal(t, "DELETE FROM users WHERE users.status = ? LIMIT ?", query)
+		require.Equal(t, []any{"x", 100}, params)
+	})
+
+	t.Run("delete using", func(t *testing.T) {
+		query, params := NewDelete(users, WithDialect(Postgres), Using("orders"), RawWhere("users.id = orders.user_id"), Where("orders.total", GreaterThan, 100))
+		require.Equal(t, "DELETE FROM users USING orders WHERE (users.id = orders.user_id) AND orders.total > $1", query)
+		require.Equal(t, []any{100}, params)
+
+		query, params = NewDelete(users, Using("orders", "carts"), RawWhere("users.id = orders.user_id"), Where("orders.total", GreaterThan, 100))
+		require.Equal(t, "DELETE users FROM users, orders, carts WHERE (users.id = orders.user_id) AND orders.total > ?", query)
+		require.Equal(t, []any{100}, params)
+	})
+
+	t.Run("delete by compound key", func(t *testing.T) {
+		query, params := NewDelete("memberships", WhereTupleIn([]DBField{"user_id", "group_id"}, [][]any{{1, 10}, {2, 20}}))
+		require.Equal(t, "DELETE FROM memberships WHERE (user_id, group_id) IN ((?,?),(?,?))", query)
+		require.Equal(t, []any{1, 10, 2, 20}, params)
+
+		_, _, err := NewDeleteE("memberships", WhereTupleIn([]DBField{"user_id", "group_id"}, [][]any{{1}}))
+		require.ErrorIs(t, err, ErrParamCount)
+	})
+
+	t.Run("delete returning", func(t *testing.T) {
+		query, params := NewDelete(users, WithDialect(Postgres), Where(status, Equal, "x"), Returning("id"))
+		require.Equal(t, "DELETE FROM users WHERE users.status = $1 RETURNING id", query)
+		require.Equal(t, []any{"x"}, params)
+	})
+}
+
+func TestNewTruncate(t *testing.T) {
+	var (
+		users DBTable = "users"
+	)
+
+	t.Run("truncate", func(t *testing.T) {
+		require.Equal(t, "TRUNCATE TABLE users", NewTruncate(users))
+	})
+
+	t.Run("truncate with modifiers", func(t *testing.T) {
+		require.Equal(t, "TRUNCATE TABLE users RESTART IDENTITY CASCADE", NewTruncate(users, Cascade(), RestartIdentity()))
+		require.Equal(t, "TRUNCATE TABLE users CASCADE", NewTruncate(users, Cascade()))
+	})
 }
 
 func TestNewUpdate(t *testing.T) {
@@ -113,6 +1393,43 @@ func TestNewUpdate(t *testing.T) {
 		require.Equal(t, "bla", params[0])
 		require.Equal(t, 2, params[1])
 	})
+
+	t.Run("set params precede where params", func(t *testing.T) {
+		var (
+			name   DBField = "users.name"
+			userID DBField = "users.id"
+		)
+
+		query, params := NewUpdate(users, Where(userID, Equal, 5), Set(name, "x"), Limit(1), Set("age", 30))
+		require.Equal(t, "UPDATE users SET name = ?, age = ? WHERE users.id = ? LIMIT ?", query)
+		require.Equal(t, []any{"x", 30, 5, 1}, params)
+
+		query, params = NewUpdate(users, Set(name, "x"), Where(userID, Equal, 5))
+		require.Equal(t, "UPDATE users SET name = ? WHERE users.id = ?", query)
+		require.Equal(t, []any{"x", 5}, params)
+	})
+
+	t.Run("update with expressions", func(t *testing.T) {
+		query, params := NewUpdate(users, Set("name", "bla"), SetExpr("views", "views + ?", 1), SetExpr("updated_at", "NOW()"), Set("age", 30), Where("users.id", Equal, 2))
+		require.Equal(t, "UPDATE users SET name = ?, views = views + ?, updated_at = NOW(), age = ? WHERE users.id = ?", query)
+		require.Equal(t, []any{"bla", 1, 30, 2}, params)
+	})
+
+	t.Run("update from", func(t *testing.T) {
+		query, params := NewUpdate(users, WithDialect(Postgres), Set("users.status", "vip"), Using("orders"), RawWhere("users.id = orders.user_id"), Where("orders.total", GreaterThan, 100))
+		require.Equal(t, "UPDATE users SET status = $1 FROM orders WHERE (users.id = orders.user_id) AND orders.total > $2", query)
+		require.Equal(t, []any{"vip", 100}, params)
+
+		query, params = NewUpdate(users, Set("users.status", "vip"), Using("orders"), RawWhere("users.id = orders.user_id"))
+		require.Equal(t, "UPDATE users, orders SET users.status = ? WHERE (users.id = orders.user_id)", query)
+		require.Equal(t, []any{"vip"}, params)
+	})
+
+	t.Run("update returning", func(t *testing.T) {
+		query, params := NewUpdate(users, WithDialect(Postgres), Set("name", "bla"), Where("users.id", Equal, 2), Returning("id", "updated_at"))
+		require.Equal(t, "UPDATE users SET name = $1 WHERE users.id = $2 RETURNING id, updated_at", query)
+		require.Equal(t, []any{"bla", 2}, params)
+	})
 }
 
 func TestNewInsert(t *testing.T) {
@@ -124,8 +1441,182 @@ func TestNewInsert(t *testing.T) {
 		status  DBField = "status"
 	)
 
-	res := NewInsert(users, []DBField{name, address, status})
-	require.Equal(t, "INSERT INTO users (name, address, status) VALUES (?, ?, ?)", res)
+	t.Run("insert fields", func(t *testing.T) {
+		res := NewInsert(users, []DBField{name, address, status})
+		require.Equal(t, "INSERT INTO users (name, address, status) VALUES (?, ?, ?)", res)
+	})
+
+	t.Run("strips the table prefix", func(t *testing.T) {
+		res := NewInsert(users, []DBField{"users.name", address, "users.users.status", "orders.users.id"})
+		require.Equal(t, "INSERT INTO users (name, address, users.status, orders.users.id) VALUES (?, ?, ?, ?)", res)
+	})
+}
+
+func TestNewInsertSelect(t *testing.T) {
+	var (
+		archive DBTable = "archive"
+		users   DBTable = "users"
+		name    DBField = "name"
+		email   DBField = "email"
+		active  DBField = "active"
+	)
+
+	sub := NewSelect(users, []DBField{name, email}, Where(active, Equal, false))
+	res, params := NewInsertSelect(archive, []DBField{"archive.name", "archive.email"}, sub)
+	require.Equal(t, "INSERT INTO archive (name, email) SELECT name, email FROM users WHERE active = ?", res)
+	require.Equal(t, []any{false}, params)
+}
+
+func TestNewInsertWith(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		name    DBField = "name"
+		address DBField = "address"
+	)
+
+	t.Run("without options", func(t *testing.T) {
+		query, params := NewInsertWith(users, []DBField{name, address})
+		require.Equal(t, "INSERT INTO users (name, address) VALUES (?, ?)", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("bound values", func(t *testing.T) {
+		query, params, err := NewInsertWithE(users, nil, Value(name, "john"), Value(address, "street"), Value("age", 30), Returning("id"))
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name, address, age) VALUES (?, ?, ?) RETURNING id", query)
+		require.Equal(t, []any{"john", "street", 30}, params)
+
+		query, params, err = NewInsertWithE(users, nil, WithDialect(Postgres), Value(name, "john"), OnConflict([]DBField{name}, SetExcluded(address)))
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET address = EXCLUDED.address", query)
+		require.Equal(t, []any{"john"}, params)
+	})
+
+	t.Run("default values", func(t *testing.T) {
+		query, params := NewInsertWith(users, nil, WithDialect(Postgres), Returning("id"))
+		require.Equal(t, "INSERT INTO users DEFAULT VALUES RETURNING id", query)
+		require.Empty(t, params)
+
+		query, params = NewInsertWith(users, nil)
+		require.Equal(t, "INSERT INTO users () VALUES ()", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("matches NewInsert", func(t *testing.T) {
+		query, _ := NewInsertWith(users, []DBField{name, address})
+		require.Equal(t, NewInsert(users, []DBField{name, address}), query)
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		_, _, err := NewInsertWithE(users, []DBField{name}, OnConflict([]DBField{name}, Set(address, "x"), Where(address, In)))
+		require.ErrorIs(t, err, ErrEmptyIn)
+	})
+
+	t.Run("returning", func(t *testing.T) {
+		query, params := NewInsertWith(users, []DBField{name, address}, WithDialect(Postgres), Returning("id", "created_at"))
+		require.Equal(t, "INSERT INTO users (name, address) VALUES ($1, $2) RETURNING id, created_at", query)
+		require.Empty(t, params)
+	})
+}
+
+func TestOnConflict(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		name  DBField = "name"
+		email DBField = "email"
+	)
+
+	t.Run("do nothing", func(t *testing.T) {
+		query, params := NewInsertWith(users, []DBField{name, email}, WithDialect(Postgres), OnConflict([]DBField{email}, DoNothing()))
+		require.Equal(t, "INSERT INTO users (name, email) VALUES ($1, $2) ON CONFLICT (email) DO NOTHING", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("do update", func(t *testing.T) {
+		query, params := NewInsertWith(users, []DBField{name, email},
+			WithDialect(Postgres),
+			OnConflict([]DBField{email}, SetExcluded("users.name"), Set("updated_by", "admin"), Where("users.locked", Equal, false)),
+			Returning("id"),
+		)
+		require.Equal(t, "INSERT INTO users (name, email) VALUES ($1, $2) ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, updated_by = $3 WHERE users.locked = $4 RETURNING id", query)
+		require.Equal(t, []any{"admin", false}, params)
+	})
+
+	t.Run("qualified columns", func(t *testing.T) {
+		query, params, err := NewInsertWithE(users, []DBField{"users.name", "users.email"},
+			WithDialect(Postgres),
+			OnConflict([]DBField{"users.email"}, SetExcluded("users.name")),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name, email) VALUES ($1, $2) ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name", query)
+		require.Empty(t, params)
+	})
+
+	t.Run("do update without columns", func(t *testing.T) {
+		_, _, err := NewInsertWithE(users, []DBField{name, email}, WithDialect(Postgres), OnConflict(nil, SetExcluded(name)))
+		require.ErrorIs(t, err, ErrConflictTarget)
+
+		query, _, err := NewInsertWithE(users, []DBField{name, email}, WithDialect(Postgres), OnConflict(nil))
+		require.NoError(t, err)
+		require.Equal(t, "INSERT INTO users (name, email) VALUES ($1, $2) ON CONFLICT DO NOTHING", query)
+	})
+}
+
+func TestSchemaQualifiedTables(t *testing.T) {
+	var (
+		events DBTable = "analytics.events"
+
+		eventID   DBField = "analytics.events.id"
+		eventName DBField = "analytics.events.name"
+	)
+
+	t.Run("insert", func(t *testing.T) {
+		res := NewInsert(events, []DBField{eventName, "analytics.events.created_at", "events.kind"})
+		require.Equal(t, "INSERT INTO analytics.events (name, created_at, events.kind) VALUES (?, ?, ?)", res)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		query, params := NewUpdate(events, Set(eventName, "signup"), Where(eventID, Equal, 1))
+		require.Equal(t, "UPDATE analytics.events SET name = ? WHERE analytics.events.id = ?", query)
+		require.Equal(t, []any{"signup", 1}, params)
+	})
+
+	t.Run("quoted", func(t *testing.T) {
+		table, err := QuoteTable(Postgres, string(events))
+		require.NoError(t, err)
+
+		field, err := QuoteField(Postgres, string(eventName))
+		require.NoError(t, err)
+
+		res := NewInsert(table, []DBField{field})
+		require.Equal(t, `INSERT INTO "analytics"."events" ("name") VALUES (?)`, res)
+	})
+}
+
+func TestNewInsertMany(t *testing.T) {
+	var (
+		users DBTable = "users"
+
+		name    DBField = "name"
+		address DBField = "address"
+	)
+
+	t.Run("one row", func(t *testing.T) {
+		res := NewInsertMany(users, []DBField{name, address}, 1)
+		require.Equal(t, "INSERT INTO users (name, address) VALUES (?, ?)", res)
+	})
+
+	t.Run("two rows", func(t *testing.T) {
+		res := NewInsertMany(users, []DBField{name, address}, 2)
+		require.Equal(t, "INSERT INTO users (name, address) VALUES (?, ?), (?, ?)", res)
+	})
+
+	t.Run("zero rows", func(t *testing.T) {
+		res := NewInsertMany(users, []DBField{name, address}, 0)
+		require.Empty(t, res)
+	})
 }
 
 func toAnySlice[T any](s []T) []any {
@@ -135,3 +1626,51 @@ func toAnySlice[T any](s []T) []any {
 	}
 	return result
 }
+
+func BenchmarkNewQuery(b *testing.B) {
+	var (
+		users  DBTable = "users"
+		orders DBTable = "orders"
+
+		userID    DBField = "users.id"
+		userName  DBField = "users.name"
+		userEmail DBField = "users.email"
+		userAge   DBField = "users.age"
+	)
+
+	fields := []DBField{userID, userName, userEmail, userAge, "orders.id", "orders.total", "orders.status", "orders.created_at"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		NewQuery(users, fields,
+			Join(orders, InnerJoin, userID, "orders.user_id"),
+			Where(userName, Like, "a%"),
+			Where(userAge, GreaterThan, 18),
+			Where(userEmail, IsNotNull),
+			Where("orders.status", In, "paid", "shipped", "delivered"),
+			Where("orders.total", Between, 10, 100),
+			OrderBy(userName, ASC),
+			OrderBy(userID, Desc),
+			Limit(10),
+			Offset(20),
+		)
+	}
+}
+
+func BenchmarkNewQueryIn(b *testing.B) {
+	var (
+		users    DBTable = "users"
+		userID   DBField = "users.id"
+		userName DBField = "users.name"
+	)
+
+	ids := make([]any, 50)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		NewQuery(users, []DBField{userID, userName}, Where(userID, In, ids...), Where(userName, IsNotNull), Limit(50))
+	}
+}
@@ -0,0 +1,36 @@
+package querier
+
+// Column is a DBField whose values have type V. Options taking a Column only accept values of that
+// type, so mismatches are caught by the compiler.
+type Column[V any] DBField
+
+// Field returns the untyped field, to be used with the rest of the builder.
+func (c Column[V]) Field() DBField {
+	return DBField(c)
+}
+
+// Table pairs a table name with its typed columns, usually a struct of Column values.
+type Table[T any] struct {
+	Name    DBTable
+	Columns T
+}
+
+// NewTable declares a table with the given columns.
+func NewTable[T any](name DBTable, columns T) Table[T] {
+	return Table[T]{Name: name, Columns: columns}
+}
+
+// Select works like NewSelect on the table.
+func (t Table[T]) Select(fields []DBField, opts ...QueryBuilderOption) *Query {
+	return NewSelect(t.Name, fields, opts...)
+}
+
+// WhereColumn works like Where but only accepts a value of the column type.
+func WhereColumn[V any](column Column[V], operation DBOperation, value V) QueryBuilderOption {
+	return Where(column.Field(), operation, value)
+}
+
+// Eq matches the rows where column equals value.
+func Eq[V any](column Column[V], value V) QueryBuilderOption {
+	return WhereColumn(column, Equal, value)
+}
@@ -0,0 +1,39 @@
+package querier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type userColumns struct {
+	ID   Column[int64]
+	Name Column[string]
+	Age  Column[int]
+}
+
+var typedUsers = NewTable[userColumns]("users", userColumns{
+	ID:   "users.id",
+	Name: "users.name",
+	Age:  "users.age",
+})
+
+func TestTypedColumns(t *testing.T) {
+	cols := typedUsers.Columns
+
+	t.Run("equality", func(t *testing.T) {
+		// Eq(cols.Age, "oops") does not compile: the value must be an int.
+		res, params := typedUsers.Select([]DBField{cols.ID.Field(), cols.Name.Field()},
+			Eq(cols.Age, 30),
+			Eq(cols.Name, "john"),
+		).Build()
+		require.Equal(t, "SELECT users.id, users.name FROM users WHERE users.age = ? AND users.name = ?", res)
+		require.Equal(t, []any{30, "john"}, params)
+	})
+
+	t.Run("other operators", func(t *testing.T) {
+		res, params := typedUsers.Select([]DBField{cols.ID.Field()}, WhereColumn(cols.ID, GreaterThan, int64(10))).Build()
+		require.Equal(t, "SELECT users.id FROM users WHERE users.id > ?", res)
+		require.Equal(t, []any{int64(10)}, params)
+	})
+}